@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/config"
+	"github.com/tjhop/mango/internal/manager"
+	"github.com/tjhop/mango/internal/shell"
+)
+
+var (
+	// config keys that only accept a fixed set of values, validated at
+	// startup
+	choiceKeys = map[string][]string{
+		"manager.run-overlap": {manager.RunOverlapAbort, manager.RunOverlapWait, manager.RunOverlapQueueOne},
+	}
+
+	// protects metricMangoRuntimeInfoLabels, which are updated at runtime
+	// on config changes
+	runtimeInfoMu sync.Mutex
+
+	// protects the log level from being changed concurrently by config
+	// changes and debug toggling
+	logLevelMu sync.Mutex
+	// log level to restore when debug logging is toggled back off
+	toggledFromLevel *slog.Level
+
+	metricMangoConfigWatched = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mango_config_file_watched",
+			Help: "A metric with a constant '1' value while the config file is being watched for changes",
+		},
+	)
+
+	metricMangoConfigReloadTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_config_reload_total",
+			Help: "A count of the total number of times the config file has been reloaded after changing",
+		},
+	)
+
+	metricMangoConfigReloadFailedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_config_reload_failed_total",
+			Help: "A count of the total number of times the reloaded config file was invalid, and changes weren't applied",
+		},
+	)
+)
+
+// updateRuntimeInfo updates a label of the runtime info metric, replacing the
+// previous series.
+func updateRuntimeInfo(label, value string) {
+	runtimeInfoMu.Lock()
+	defer runtimeInfoMu.Unlock()
+
+	metricMangoRuntimeInfoLabels[label] = value
+	metricMangoRuntimeInfo.Reset()
+	metricMangoRuntimeInfo.With(metricMangoRuntimeInfoLabels).Set(1)
+}
+
+// validateChoices checks that the config keys with a fixed set of valid values
+// (see `choiceKeys`) are set to one of them. Values are compared case
+// insensitively, the same as when they're used. Errors include the offending
+// key.
+func validateChoices() error {
+	var errs []error
+	for key, choices := range choiceKeys {
+		if val := normalizeStringFlag(viper.GetString(key)); !slices.Contains(choices, val) {
+			errs = append(errs, fmt.Errorf("Invalid value for %s: %q, must be one of: [%s]", key, viper.GetString(key), strings.Join(choices, ", ")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateScriptLimits checks that the global script memory limit, if set,
+// isn't below the minimum supported memory limit. Sizes that fail to parse are
+// reported by `config.Validate`.
+func validateScriptLimits() error {
+	memory, err := config.GetSize("manager.script-memory-limit")
+	if err != nil {
+		return nil
+	}
+
+	if err := shell.ValidateMemoryLimit(memory); err != nil {
+		return fmt.Errorf("Invalid value for manager.script-memory-limit: %v", err)
+	}
+
+	return nil
+}
+
+// parseLogLevel parses the provided log level, as set with `logging.level`.
+func parseLogLevel(level string) (slog.Level, error) {
+	switch normalizeStringFlag(level) {
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("Unsupported log level: %s", level)
+	}
+}
+
+// watchConfig watches the config file for changes, and applies the settings
+// that are safe to change at runtime: the log level and the inventory
+// auto-reload schedule/interval (which is sent to the auto-reload routine on
+// autoReloadCh). Any other changed settings only take effect after a restart.
+// The file is watched with a separate viper instance, as the global instance
+// isn't safe to write to concurrently with the reads made by runs.
+func watchConfig(ctx context.Context, logger *slog.Logger, logLevel *slog.LevelVar, autoReloadCh chan autoReload, path string) {
+	v, err := config.ReadConfigFile(path, flag.CommandLine)
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to watch config file",
+			slog.String("err", err.Error()),
+			slog.String("path", path),
+		)
+		return
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		metricMangoConfigReloadTotal.Inc()
+		logger.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"Config file changed, applying runtime settings",
+			slog.String("path", e.Name),
+		)
+
+		applyConfig(ctx, logger, logLevel, autoReloadCh, v)
+	})
+	v.WatchConfig()
+	metricMangoConfigWatched.Set(1)
+}
+
+// applyConfig applies the values of the settings that can be changed at
+// runtime from the config in the viper instance. Invalid values are logged and
+// ignored, leaving the current settings in place.
+func applyConfig(ctx context.Context, logger *slog.Logger, logLevel *slog.LevelVar, autoReloadCh chan autoReload, v *viper.Viper) {
+	ar, err := getAutoReload(v)
+	if err != nil {
+		metricMangoConfigReloadFailedTotal.Inc()
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to apply config, keeping current settings",
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	levelVal := v.GetString("logging.level")
+	level, err := parseLogLevel(levelVal)
+	if err != nil {
+		metricMangoConfigReloadFailedTotal.Inc()
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to apply config, keeping current settings",
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+	if level != logLevel.Level() {
+		// an explicitly configured level overrides any debug toggle
+		toggledFromLevel = nil
+		logLevel.Set(level)
+		updateRuntimeInfo("log_level", normalizeStringFlag(levelVal))
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Log level changed",
+			slog.String("log_level", level.String()),
+		)
+	}
+
+	// replace any pending settings that the auto-reload routine hasn't
+	// picked up yet
+	select {
+	case <-autoReloadCh:
+	default:
+	}
+	select {
+	case autoReloadCh <- ar:
+	default:
+	}
+}
+
+// toggleDebugLogging switches the log level to debug, or back to the previous
+// log level if debug logging was previously toggled on, so that debug logs can
+// be enabled temporarily for live troubleshooting (ie, with SIGUSR1).
+func toggleDebugLogging(ctx context.Context, logger *slog.Logger, logLevel *slog.LevelVar) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+
+	level := slog.LevelDebug
+	if toggledFromLevel != nil {
+		level = *toggledFromLevel
+		toggledFromLevel = nil
+	} else {
+		prev := logLevel.Level()
+		toggledFromLevel = &prev
+	}
+
+	logLevel.Set(level)
+	updateRuntimeInfo("log_level", strings.ToLower(level.String()))
+	// logged at warn, so that it's visible regardless of the new level
+	logger.LogAttrs(
+		ctx,
+		slog.LevelWarn,
+		"Log level toggled",
+		slog.String("log_level", level.String()),
+	)
+}
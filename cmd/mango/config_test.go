@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{level: "debug", want: slog.LevelDebug},
+		{level: "info", want: slog.LevelInfo},
+		{level: "warn", want: slog.LevelWarn},
+		{level: "warning", want: slog.LevelWarn},
+		{level: "error", want: slog.LevelError},
+		{level: "DEBUG", want: slog.LevelDebug},
+		{level: " Warning ", want: slog.LevelWarn},
+		{level: "", want: slog.LevelInfo, wantErr: true},
+		{level: "trace", want: slog.LevelInfo, wantErr: true},
+		{level: "err", want: slog.LevelInfo, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := parseLogLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLogLevel(%q) error = %v, wantErr %t", tt.level, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLogLevel(%q) = %s, want %s", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateChoices(t *testing.T) {
+	tests := []struct {
+		runOverlap string
+		wantErr    bool
+	}{
+		{runOverlap: "abort"},
+		{runOverlap: "wait"},
+		{runOverlap: "queue-one"},
+		{runOverlap: " Queue-One "},
+		{runOverlap: "", wantErr: true},
+		{runOverlap: "queue", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.runOverlap, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			viper.Set("manager.run-overlap", tt.runOverlap)
+
+			if err := validateChoices(); (err != nil) != tt.wantErr {
+				t.Errorf("validateChoices() with run-overlap %q error = %v, wantErr %t", tt.runOverlap, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateScriptLimits(t *testing.T) {
+	tests := []struct {
+		memory  string
+		wantErr bool
+	}{
+		{memory: ""},
+		{memory: "16MiB"},
+		{memory: "1GiB"},
+		{memory: "1MiB", wantErr: true},
+		{memory: "512KiB", wantErr: true},
+		// reported by config.Validate
+		{memory: "lots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.memory, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			viper.Set("manager.script-memory-limit", tt.memory)
+
+			if err := validateScriptLimits(); (err != nil) != tt.wantErr {
+				t.Errorf("validateScriptLimits() with memory limit %q error = %v, wantErr %t", tt.memory, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tjhop/mango/internal/manager"
+)
+
+// eventsHandler returns an HTTP handler that streams the manager's converge
+// progress events to the client as server-sent events, with each event's data
+// encoded as JSON. Streams are closed when the shutdown channel is closed.
+func eventsHandler(mgr *manager.Manager, shutdown <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		events, unsubscribe := mgr.Subscribe()
+		defer unsubscribe()
+
+		// the server's write timeout is far too short for a long lived
+		// stream, so keep extending the deadline as we write
+		keepalive := time.NewTicker(15 * time.Second)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-shutdown:
+				return
+			case <-keepalive.C:
+				_ = rc.SetWriteDeadline(time.Now().Add(5 * time.Second))
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case e := <-events:
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+
+				_ = rc.SetWriteDeadline(time.Now().Add(5 * time.Second))
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,8 +11,10 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -24,8 +27,10 @@ import (
 
 	"github.com/tjhop/mango/pkg/utils"
 
+	"github.com/tjhop/mango/internal/config"
 	"github.com/tjhop/mango/internal/inventory"
 	"github.com/tjhop/mango/internal/manager"
+	"github.com/tjhop/mango/internal/shell"
 	"github.com/tjhop/mango/internal/version"
 )
 
@@ -42,6 +47,15 @@ const (
 )
 
 var (
+	// config keys that are valid in the config file, but aren't flags
+	configOnlyKeys = []string{"mango.temp-dir", "metrics.interface", "metrics.port"}
+
+	// config keys with duration/size/schedule values, validated at startup
+	durationKeys = []string{"inventory.reload-interval", "manager.script-cpu-limit", "manager.run-timeout", "manager.compress-run-logs-age", "manager.circuit-breaker-cooldown", "manager.run-overlap-timeout"}
+	sizeKeys     = []string{"manager.script-memory-limit", "manager.max-output-size"}
+	scheduleKeys = []string{"inventory.schedule"}
+	modeKeys     = []string{"manager.file-mode", "manager.dir-mode"}
+
 	metricMangoRuntimeInfoLabels = prometheus.Labels{
 		"auto_reload": "disabled",
 		"log_level":   "info",
@@ -54,6 +68,14 @@ var (
 		},
 		[]string{"auto_reload", "log_level"},
 	)
+
+	metricMangoProcessInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_process_info",
+			Help: "A metric with a constant '1' value with labels for the user/group that mango is running as",
+		},
+		[]string{"uid", "gid", "username", "groupname"},
+	)
 )
 
 func init() {
@@ -73,7 +95,7 @@ func init() {
 	)
 }
 
-func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname string) {
+func mango(ctx context.Context, logger *slog.Logger, logLevel *slog.LevelVar, inventoryPath, hostname string) {
 	metricMangoRuntimeInfo.With(metricMangoRuntimeInfoLabels).Set(1)
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -93,6 +115,10 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 		"Mango server started",
 	)
 
+	// set before anything is started, as viper isn't safe to write to
+	// concurrently with the reads made by runs
+	viper.SetDefault("metrics.port", defaultPrometheusPort)
+
 	// create directory for persistent logs
 	logDir := filepath.Join("/var/log", programName)
 	err := os.MkdirAll(logDir, 0755)
@@ -143,8 +169,15 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 		"Initializing mango inventory",
 	)
 	inv := inventory.NewInventory(inventoryPath, hostname)
-	// reload inventory
-	inv.Reload(ctx, inventoryLogger)
+	// the inventory is reloaded both on SIGHUP and by the auto-reload
+	// timer, so serialize reloads to keep them from clobbering each other
+	var invReloadLock sync.Mutex
+	reloadInventory := func() {
+		invReloadLock.Lock()
+		defer invReloadLock.Unlock()
+		inv.Reload(ctx, inventoryLogger)
+	}
+	reloadInventory()
 
 	// start manager, reload it with data from inventory, and then start a run of everything for the system
 	managerLogger := logger.With(slog.String("worker", "manager"))
@@ -163,6 +196,12 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 	mgr.ReloadAndRunAll(ctx, managerLogger, inv)
 
 	reloadCh := make(chan struct{})
+	// buffered, so that config changes never block on the auto-reload routine
+	autoReloadCh := make(chan autoReload, 1)
+	if viper.ConfigFileUsed() != "" {
+		watchConfig(ctx, logger, logLevel, autoReloadCh, viper.ConfigFileUsed())
+	}
+
 	var g run.Group
 	{
 		// termination and cleanup
@@ -221,8 +260,28 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 							slog.String("signal", sig.String()),
 						)
 
+						// reload config and apply runtime settings. The
+						// config is read into a separate viper
+						// instance, as the global instance isn't safe
+						// to write to concurrently with the reads made
+						// by runs
+						if path := viper.ConfigFileUsed(); path != "" {
+							if v, err := config.ReadConfigFile(path, flag.CommandLine); err != nil {
+								metricMangoConfigReloadFailedTotal.Inc()
+								logger.LogAttrs(
+									ctx,
+									slog.LevelError,
+									"Failed to reload config file",
+									slog.String("err", err.Error()),
+								)
+							} else {
+								metricMangoConfigReloadTotal.Inc()
+								applyConfig(ctx, logger, logLevel, autoReloadCh, v)
+							}
+						}
+
 						// reload inventory
-						inv.Reload(ctx, inventoryLogger)
+						reloadInventory()
 
 						// signal the manager runner
 						// goroutine that a reload
@@ -241,6 +300,28 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 			},
 		)
 	}
+	{
+		// debug logging toggle
+		usr1 := make(chan os.Signal, 1)
+		signal.Notify(usr1, syscall.SIGUSR1)
+		cancel := make(chan struct{})
+		g.Add(
+			func() error {
+				for {
+					select {
+					case <-usr1:
+						toggleDebugLogging(ctx, logger, logLevel)
+					case <-cancel:
+						return nil
+					}
+				}
+			},
+			func(error) {
+				signal.Stop(usr1)
+				close(cancel)
+			},
+		)
+	}
 	{
 		// manager runner
 		cancel := make(chan struct{})
@@ -265,62 +346,81 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 		)
 	}
 	{
-		// ticker routine for auto reload, if configured
+		// timer routine for auto reload, if configured
 		cancel := make(chan struct{})
 		g.Add(
 			func() error {
-				interval := viper.GetString("inventory.reload-interval")
-				if interval == "" {
-					// auto update not enabled, log and carry on
-					logger.LogAttrs(
-						ctx,
-						slog.LevelInfo,
-						"Inventory auto-reload is not enabled, mango will only re-apply inventory if sent a SIGHUP",
-					)
-					<-cancel
-				} else {
-					// auto update enabled, attempt to configure or carry on
-					dur, err := time.ParseDuration(interval)
-					if err != nil {
+				// validated at startup
+				ar, _ := getAutoReload(viper.GetViper())
+
+				// the schedule/interval can be changed at runtime via
+				// the config file, so the timer is (re)armed as needed
+				var (
+					timer   *time.Timer
+					timerCh <-chan time.Time
+				)
+				arm := func() {
+					if timer != nil {
+						timer.Stop()
+						timer, timerCh = nil, nil
+					}
+
+					if !ar.enabled() {
+						metricManagerNextRunTimestamp.Set(0)
+						return
+					}
+
+					next := ar.next(time.Now())
+					metricManagerNextRunTimestamp.Set(float64(next.Unix()))
+					timer = time.NewTimer(time.Until(next))
+					timerCh = timer.C
+				}
+				setAutoReload := func() {
+					if !ar.enabled() {
+						// auto update not enabled, log and carry on
 						logger.LogAttrs(
 							ctx,
-							slog.LevelError,
-							"Failed to parse duration for inventory auto-reload, continuing without enabling",
-							slog.String("err", err.Error()),
+							slog.LevelInfo,
+							"Inventory auto-reload is not enabled, mango will only re-apply inventory if sent a SIGHUP",
+						)
+					} else {
+						logger.LogAttrs(
+							ctx,
+							slog.LevelInfo,
+							"Inventory auto-reload enabled",
+							ar.logAttr(),
 						)
-
-						return nil
 					}
+					updateRuntimeInfo("auto_reload", ar.String())
+					arm()
+				}
+				setAutoReload()
+				defer func() {
+					if timer != nil {
+						timer.Stop()
+					}
+				}()
 
-					logger.LogAttrs(
-						ctx,
-						slog.LevelInfo,
-						"Inventory auto-reload enabled",
-						slog.String("interval", dur.String()),
-					)
-
-					// update runtime info metric
-					metricMangoRuntimeInfoLabels["auto_reload"] = dur.String()
-					metricMangoRuntimeInfo.With(metricMangoRuntimeInfoLabels).Set(1)
-
-					ticker := time.NewTicker(dur)
-
-					for {
-						select {
-						case <-ticker.C:
-							logger.LogAttrs(
-								ctx,
-								slog.LevelInfo,
-								"Inventory auto-reload signal received, reloading inventory and rerunning modules",
-							)
-							mgr.ReloadAndRunAll(ctx, managerLogger, inv)
-						case <-cancel:
-							return nil
+				for {
+					select {
+					case <-timerCh:
+						logger.LogAttrs(
+							ctx,
+							slog.LevelInfo,
+							"Inventory auto-reload signal received, reloading inventory and rerunning modules",
+						)
+						reloadInventory()
+						mgr.ReloadAndRunAll(ctx, managerLogger, inv)
+						arm()
+					case newAR := <-autoReloadCh:
+						if !newAR.equal(ar) {
+							ar = newAR
+							setAutoReload()
 						}
+					case <-cancel:
+						return nil
 					}
 				}
-
-				return nil
 			},
 			func(error) {
 				close(cancel)
@@ -331,7 +431,6 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 		// web server for metrics/pprof
 		cancel := make(chan struct{})
 
-		viper.SetDefault("metrics.port", defaultPrometheusPort)
 		iface := viper.GetString("metrics.interface")
 		port := viper.GetInt("metrics.port")
 		address := fmt.Sprintf("%s:%d", iface, port)
@@ -345,6 +444,11 @@ func mango(ctx context.Context, logger *slog.Logger, inventoryPath, hostname str
 		}
 		http.Handle("/metrics", promhttp.Handler())
 
+		// converge progress event stream
+		eventsShutdown := make(chan struct{})
+		metricsServer.RegisterOnShutdown(func() { close(eventsShutdown) })
+		http.Handle("/events", eventsHandler(mgr, eventsShutdown))
+
 		g.Add(
 			func() error {
 				if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
@@ -413,16 +517,108 @@ func cleanup(ctx context.Context, logger *slog.Logger) {
 	}
 }
 
+// validateConfigKeys checks the config file for unknown keys (ie, typos), which
+// would otherwise be silently ignored. Unknown keys are logged, and mango exits
+// if `config.strict` is enabled.
+func validateConfigKeys(ctx context.Context, logger *slog.Logger, configFile string) {
+	known := slices.Clone(configOnlyKeys)
+	flag.VisitAll(func(f *flag.Flag) {
+		known = append(known, f.Name)
+	})
+
+	unknown, err := config.UnknownKeys(configFile, known)
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to validate config file",
+			slog.String("err", err.Error()),
+			slog.String("path", configFile),
+		)
+		os.Exit(1)
+	}
+
+	level := slog.LevelWarn
+	if viper.GetBool("config.strict") {
+		level = slog.LevelError
+	}
+
+	for _, key := range unknown {
+		attrs := []slog.Attr{
+			slog.String("key", key),
+			slog.String("path", configFile),
+		}
+		if suggestion := config.SuggestKey(key, known); suggestion != "" {
+			attrs = append(attrs, slog.String("did_you_mean", suggestion))
+		}
+
+		logger.LogAttrs(ctx, level, "Unknown key in config file, it will be ignored", attrs...)
+	}
+
+	if len(unknown) > 0 && viper.GetBool("config.strict") {
+		os.Exit(1)
+	}
+}
+
 func main() {
 	// prep and parse flags
+	flag.Bool("config.strict", false, "If enabled, mango will refuse to start if the config file contains unknown keys, rather than logging a warning")
+	flag.String("config.file", "", "Path to a config file to read settings from. Flags take precedence over config file settings [default 'mango.yaml' in /etc/mango, $HOME/mango, or the working directory, if present]")
 	flag.StringP("inventory.path", "i", "", "Path to mango configuration inventory")
 	flag.String("inventory.reload-interval", "", "Time duration for how frequently mango will auto reload and apply the inventory [default disabled]")
-	flag.StringP("logging.level", "l", "info", "Logging level may be one of: [debug, info, warning, error]")
+	flag.String("inventory.schedule", "", "Cron expression (ie, '*/30 9-17 * * 1-5' or '@hourly') for when mango will auto reload and apply the inventory, evaluated in local time. Takes precedence over '--inventory.reload-interval' [default disabled]")
+	flag.String("inventory.source", "", "Remote inventory source (git URL, or rsync/SSH path) to sync into '--inventory.path' before each reload. If a sync fails, the last synced copy is used [default disabled]")
+	flag.String("inventory.source-type", "", "Type of the remote inventory source. May be one of: [git, rsync] [default detected from the source]")
+	flag.String("inventory.source-ref", "", "Branch or tag of the remote git inventory source to sync [default remote HEAD]")
+	flag.String("inventory.source-ssh-key", "", "Path to an SSH private key to use when syncing the remote inventory source")
+	flag.String("inventory.source-token-file", "", "Path to a file containing a token to send as a bearer token when syncing the remote git inventory source over HTTP(S)")
+	flag.String("inventory.variable-precedence", inventory.PrecedenceHost, "Which level of the inventory wins when variables/templates are defined at multiple levels. May be one of: [host, role]. 'host' applies role, then group, then host data (host wins), 'role' applies the reverse (role wins)")
+	flag.StringP("logging.level", "l", "info", "Logging level may be one of: [debug, info, warn, error], 'warning' is accepted as an alias for 'warn'")
 	flag.String("logging.output", "logfmt", "Logging format may be one of: [logfmt, json]")
+	flag.String("inventory.hostname-source", utils.HostnameSourceSystem, "Source of the hostname used to look up the system in the inventory. May be one of: [system, fqdn, file, command]. Overridden by '--hostname'")
+	flag.String("inventory.hostname-source-path", "", "Path of the file to read the hostname from, when using the 'file' hostname source")
+	flag.String("inventory.hostname-source-command", "", "Command to run with 'sh -c' that prints the hostname, when using the 'command' hostname source")
+	flag.StringSlice("inventory.hostname-aliases", nil, "Comma separated list of additional names the system may be known by in the inventory. If the hostname isn't enrolled, the first enrolled alias is used")
+	flag.String("inventory.hostname-aliases-file", "", "Path to a newline delimited file of additional names the system may be known by in the inventory, appended to '--inventory.hostname-aliases'")
+	flag.Bool("inventory.case-insensitive-hostnames", false, "If enabled, hostnames are compared case insensitively when looking up hosts and matching group globs/regexes in the inventory")
 	flag.String("hostname", "", "(Requires root) Custom hostname to use [default is system hostname]")
 	flag.Bool("manager.skip-apply-on-test-success", false, "If enabled, this will allow mango to skip running the module's idempotent `apply` script if the `test` script passes without issues")
+	flag.Bool("manager.template-error-fatal", false, "If enabled, any template error (ie, from a broken shared template) aborts the whole run, and the remaining directives/modules are skipped. By default, template errors only fail the directive/module being templated")
+	flag.String("manager.template-seed", "", "If set, template random functions (ie, 'randAlphaNum', 'randInt', 'uuidv4') are seeded deterministically from this value, the hostname, and the module name, so that repeated renders of a module on a host are stable [default nondeterministic]")
+	flag.Bool("manager.randomize-order", false, "If enabled, mango will randomize the run order of modules that don't require each other, to help catch missing module requirements")
+	flag.String("manager.disable-file", "/etc/mango/disabled", "Path to a host-local file that, while present, causes mango to skip scheduled and SIGHUP triggered runs, ie while an operator does manual work on the host. Set to an empty string to disable the check")
+	flag.Bool("manager.run-when-not-enrolled", false, "If enabled, mango will reload and run even when the host is not enrolled in the inventory (ie, for testing). By default, runs are skipped for hosts that aren't enrolled")
+	flag.Bool("manager.incremental-converge", false, "If enabled, mango will only run modules (and their dependents) that have changed since their last successful run, rather than all modules on every run. Local drift on the host is not corrected for unchanged modules")
+	flag.Bool("manager.force-full-converge", false, "If enabled, mango will run all modules on every run, even if '--manager.incremental-converge' is enabled")
+	flag.String("manager.script-cpu-limit", "", "Maximum CPU time each external command run by a script may consume, as a duration (ie, '30s'). Only supported on Linux. Can be overridden per module with a 'limits' file [default unlimited]")
+	flag.String("manager.script-memory-limit", "", "Maximum virtual address space size of each external command run by a script (ie, '512MiB'). Must be at least 16MiB, as it includes the command's shared libraries. Only supported on Linux. Can be overridden per module with a 'limits' file [default unlimited]")
+	flag.String("manager.run-timeout", "", "Maximum duration of a whole run (ie, '30m'). Once exceeded, running scripts are canceled and the remaining modules are skipped until the next run [default unlimited]")
+	flag.Bool("manager.keep-failed-run-workdirs", false, "If enabled, mango will keep the ephemeral working directory that scripts were run in after a failed run, for debugging. By default, it's removed once the run finishes")
+	flag.Int("manager.circuit-breaker-threshold", 0, "Number of consecutive failed runs after which a module is skipped for '--manager.circuit-breaker-cooldown', before being retried once. Set to 0 to disable")
+	flag.String("manager.circuit-breaker-cooldown", "1h", "How long a module is skipped for once its circuit breaker opens, as a duration (ie, '30m')")
+	flag.Bool("manager.force", false, "If enabled, mango will run modules even if their circuit breaker is open")
+	flag.Bool("manager.create-module-workdirs", false, "If enabled, mango will create the working directory set in a module's 'workdir' file if it doesn't exist, rather than failing the module")
+	flag.String("manager.notify", "", "Webhook URL to POST a JSON summary of each run's results to [default disabled]")
+	flag.String("manager.notify-template", "", "Path to a Go text/template file used to render the run notification body, with the run report as its data [default JSON encoded run report]")
+	flag.Int("manager.notify-retries", 3, "Number of times to retry sending a run notification on transient failures, with exponential backoff")
+	flag.String("manager.on-failure", "", "Path to a script to run whenever a module fails, for custom alerting or remediation. Failure details are provided to the script as environment variables, ie 'MANGO_FAILED_MODULE', 'MANGO_EXIT_CODE', 'MANGO_ERROR', and 'MANGO_RUN_ID' [default disabled]")
+	flag.String("manager.script-path", "", "PATH to run scripts with, ie '/usr/local/sbin:/usr/local/bin'. Useful when mango inherits a minimal PATH (ie, from systemd). PATHs set in a script's variables or by the script itself take precedence [default inherited PATH]")
+	flag.String("manager.script-path-mode", shell.ScriptPathModePrepend, "How '--manager.script-path' is applied to the inherited PATH. May be one of: [prepend, override]")
+	flag.String("manager.keep-rendered-scripts", shell.KeepRenderedScriptsAlways, "When to write the rendered copy of each script to the script's log dir as 'script.mango-rendered'. Disable for scripts that render secrets. May be one of: [always, on-failure, never]")
+	flag.Bool("manager.compress-run-logs", false, "If enabled, mango will gzip compress the script logs of runs older than '--manager.compress-run-logs-age' after each run, to save disk space while keeping them for auditing")
+	flag.String("manager.compress-run-logs-age", "24h", "Minimum age of a run before its script logs are compressed, as a duration (ie, '72h'), if '--manager.compress-run-logs' is enabled")
+	flag.String("manager.max-output-size", "", "Maximum size of each of a script's stdout/stderr log files (ie, '10MiB'), after which the output is truncated with a marker, to protect hosts from runaway script output. A cap is recommended [default unlimited]")
+	flag.String("manager.file-mode", "0644", "Octal permission mode of files created for script runs (ie, the exit_status logs). The stdout/stderr logs and rendered scripts are further restricted to the owner (and '--manager.log-group'). Subject to the umask")
+	flag.String("manager.log-group", "", "Group name or GID to own the stdout/stderr logs and rendered scripts of script runs, which are then created group readable (0640) rather than owner only (0600)")
+	flag.String("manager.dir-mode", "0750", "Octal permission mode of directories created for script runs (ie, script log dirs, ephemeral working dirs, and created module workdirs). Subject to the umask")
+	flag.String("manager.procfs-path", "/proc", "Path procfs is mounted at, for collecting system metadata (ie, when running in a container with the host's procfs mounted elsewhere)")
+	flag.String("manager.sysfs-path", "/sys", "Path sysfs is mounted at, for collecting system metadata")
+	flag.String("manager.run-overlap", manager.RunOverlapAbort, "What to do when a run is requested (ie, by auto-reload or SIGHUP) while a run is already in progress: 'abort' drops it, 'wait' waits for the run in progress to finish, and 'queue-one' queues it to start once the run in progress finishes, coalescing further requests. May be one of: [abort, wait, queue-one]")
+	flag.String("manager.run-overlap-timeout", "5m", "Maximum time to wait for a run in progress to finish when '--manager.run-overlap' is 'wait', as a duration (ie, '10m'). Set to '0' to wait indefinitely")
+	flag.Int("manager.stderr-tail-lines", 10, "Number of trailing lines of a failed module script's stderr to include in the failure log/error. Set to 0 to disable")
 	flag.BoolP("help", "h", false, "Prints help and usage information")
 	flag.BoolP("version", "v", false, "Prints version and build info")
+	flag.String("output", "text", "Output format for '--version', may be one of: [text, json]")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s\nUsage of %s:\n", programNameAsciiArt, os.Args[0])
@@ -441,10 +637,27 @@ func main() {
 	}
 
 	if viper.GetBool("version") {
-		fmt.Println(version.Print(programName))
+		switch normalizeStringFlag(viper.GetString("output")) {
+		case "json":
+			info, err := version.PrintJSON()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Print(info)
+		default:
+			fmt.Println(version.Print(programName))
+		}
 		os.Exit(0)
 	}
 
+	config.AutomaticEnv()
+	configFile, err := config.ReadInConfig(viper.GetString("config.file"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	rootCtx := context.Background()
 
 	logLevel := new(slog.LevelVar) // default to info level logging
@@ -475,31 +688,45 @@ func main() {
 
 	// parse log level from flag
 	logLevelFlagVal := normalizeStringFlag(viper.GetString("logging.level"))
-	switch logLevelFlagVal {
-	case "":
+	if logLevelFlagVal == "" {
 		logLevel.Set(slog.LevelInfo)
 		logger.LogAttrs(rootCtx, slog.LevelWarn, "Log level flag not set, defaulting to <info> level")
-	case "info": // default is info, we're good
-	case "warn":
-		logLevel.Set(slog.LevelWarn)
-	case "debug":
-		logLevel.Set(slog.LevelDebug)
-	case "error":
-		logLevel.Set(slog.LevelError)
-	default:
+	} else if level, err := parseLogLevel(logLevelFlagVal); err != nil {
 		logLevel.Set(slog.LevelInfo)
 		logger.LogAttrs(
 			rootCtx,
 			slog.LevelWarn,
 			"Failed to parse log level from flag, defaulting to <info> level",
-			slog.String("err", "Unsupported log level"),
+			slog.String("err", err.Error()),
 			slog.String("log_level", logLevelFlagVal),
 		)
+	} else {
+		logLevel.Set(level)
+	}
+
+	if configFile != "" {
+		logger.LogAttrs(
+			rootCtx,
+			slog.LevelInfo,
+			"Read settings from config file",
+			slog.String("path", configFile),
+		)
+
+		validateConfigKeys(rootCtx, logger, configFile)
+	}
+
+	if err := errors.Join(config.Validate(durationKeys, sizeKeys, scheduleKeys, modeKeys), validateChoices(), validateScriptLimits()); err != nil {
+		logger.LogAttrs(
+			rootCtx,
+			slog.LevelError,
+			"Failed to validate settings",
+			slog.String("err", err.Error()),
+		)
+		os.Exit(1)
 	}
 
 	// update runtime info metric
-	metricMangoRuntimeInfoLabels["log_level"] = logLevelFlagVal
-	metricMangoRuntimeInfo.With(metricMangoRuntimeInfoLabels).Set(1)
+	updateRuntimeInfo("log_level", logLevelFlagVal)
 
 	// ensure inventory is set
 	inventoryPath := viper.GetString("inventory.path")
@@ -513,8 +740,25 @@ func main() {
 		os.Exit(1)
 	}
 
-	// get hostname for inventory
-	me := utils.GetHostname()
+	// get hostname for inventory from the configured source, falling back
+	// to the system hostname
+	hostnameSource := normalizeStringFlag(viper.GetString("inventory.hostname-source"))
+	hostnameSourceArg := viper.GetString("inventory.hostname-source-path")
+	if hostnameSource == utils.HostnameSourceCommand {
+		hostnameSourceArg = viper.GetString("inventory.hostname-source-command")
+	}
+	me, err := utils.ResolveHostname(hostnameSource, hostnameSourceArg)
+	if err != nil {
+		me = utils.GetHostname()
+		logger.LogAttrs(
+			rootCtx,
+			slog.LevelWarn,
+			"Failed to get hostname from configured source, falling back to system hostname",
+			slog.String("err", err.Error()),
+			slog.String("hostname_source", hostnameSource),
+			slog.String("hostname", me),
+		)
+	}
 
 	// only allow setting custom hostname if running as root
 	if os.Geteuid() == 0 {
@@ -543,11 +787,49 @@ func main() {
 		),
 	)
 
+	// log and expose the user/group mango is running as, to help diagnose
+	// permission issues in modules. lookup failures aren't fatal, just
+	// report what we have.
+	userInfo, err := utils.GetCurrentUserInfo()
+	if err != nil {
+		mainLogger.LogAttrs(
+			rootCtx,
+			slog.LevelWarn,
+			"Failed to resolve user/group names for mango process, using numeric IDs",
+			slog.String("err", err.Error()),
+		)
+	}
+	mainLogger.LogAttrs(
+		rootCtx,
+		slog.LevelInfo,
+		"Mango process information",
+		slog.Group("user",
+			slog.String("uid", userInfo.UID),
+			slog.String("gid", userInfo.GID),
+			slog.String("username", userInfo.Username),
+			slog.String("groupname", userInfo.Groupname),
+		),
+	)
+	metricMangoProcessInfo.With(prometheus.Labels{
+		"uid":       userInfo.UID,
+		"gid":       userInfo.GID,
+		"username":  userInfo.Username,
+		"groupname": userInfo.Groupname,
+	}).Set(1)
+
+	if os.Geteuid() != 0 {
+		mainLogger.LogAttrs(
+			rootCtx,
+			slog.LevelWarn,
+			"Mango is not running as root, modules that require elevated privileges may fail with permission errors",
+		)
+	}
+
 	// set mainLogger as default
 	slog.SetDefault(mainLogger)
 
 	// run mango daemon
-	mango(rootCtx, mainLogger, inventoryPath, me)
+	mango(rootCtx, mainLogger, logLevel, inventoryPath, me)
 }
 
 func normalizeStringFlag(s string) string {
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/config"
+)
+
+var (
+	metricManagerNextRunTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mango_manager_next_run_timestamp",
+			Help: "Timestamp of the next scheduled auto-reload and run of the inventory, in seconds since the epoch. 0 if auto-reload is disabled",
+		},
+	)
+)
+
+// autoReload is the configured cadence for automatically reloading and
+// applying the inventory: either a cron schedule (`inventory.schedule`) or a
+// fixed interval (`inventory.reload-interval`). A schedule takes precedence
+// over an interval, and auto-reload is disabled if neither is set.
+type autoReload struct {
+	interval time.Duration
+	spec     string
+	schedule cron.Schedule
+}
+
+// getAutoReload parses the auto-reload settings from the config in the viper
+// instance.
+func getAutoReload(v *viper.Viper) (autoReload, error) {
+	schedule, err := config.ParseSchedule("inventory.schedule", v.GetString("inventory.schedule"))
+	if err != nil {
+		return autoReload{}, err
+	}
+	if schedule != nil {
+		return autoReload{spec: v.GetString("inventory.schedule"), schedule: schedule}, nil
+	}
+
+	interval, err := config.ParseDuration("inventory.reload-interval", v.GetString("inventory.reload-interval"))
+	if err != nil {
+		return autoReload{}, err
+	}
+
+	return autoReload{interval: interval}, nil
+}
+
+func (ar autoReload) enabled() bool { return ar.schedule != nil || ar.interval > 0 }
+
+func (ar autoReload) equal(other autoReload) bool {
+	return ar.interval == other.interval && ar.spec == other.spec
+}
+
+// next returns the time of the next auto-reload after now.
+func (ar autoReload) next(now time.Time) time.Time {
+	if ar.schedule != nil {
+		return ar.schedule.Next(now)
+	}
+
+	return now.Add(ar.interval)
+}
+
+// String returns the schedule or interval, for logging and runtime info.
+func (ar autoReload) String() string {
+	switch {
+	case ar.schedule != nil:
+		return ar.spec
+	case ar.interval > 0:
+		return ar.interval.String()
+	default:
+		return "disabled"
+	}
+}
+
+func (ar autoReload) logAttr() slog.Attr {
+	if ar.schedule != nil {
+		return slog.String("schedule", ar.spec)
+	}
+
+	return slog.String("interval", ar.interval.String())
+}
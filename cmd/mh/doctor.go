@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	kernelParser "github.com/moby/moby/pkg/parsers/kernel"
+	"github.com/prometheus/procfs"
+	"github.com/prometheus/procfs/blockdevice"
+	distro "github.com/quay/claircore/osrelease"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+const (
+	// keep in sync with the paths that mango uses
+	doctorLogDir  = "/var/log/mango"
+	doctorProcDir = "/proc"
+	doctorSysDir  = "/sys"
+)
+
+var (
+	doctorCmd = &cobra.Command{
+		Use:     "doctor",
+		Aliases: []string{"self-test", "check"},
+		Short:   "Command to verify the runtime environment that mango needs",
+		Long:    "Command to verify the runtime environment that mango needs, such as the log/temp directories, system metadata sources, and the inventory. Prints a pass/fail checklist and exits non-zero if any check fails.",
+		Args:    cobra.ExactArgs(0),
+		Run:     doctor,
+	}
+)
+
+func init() {
+	doctorCmdFlagSet := doctorCmd.Flags()
+	// not bound to viper, to avoid clobbering the `inventory` command's
+	// binding of the same flag
+	doctorCmdFlagSet.StringP("inventory.path", "i", "", "Path to mango configuration inventory to check")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+type doctorCheck struct {
+	name string
+	run  func() (string, error)
+}
+
+func doctor(cmd *cobra.Command, args []string) {
+	inventoryPath, _ := cmd.Flags().GetString("inventory.path")
+	if inventoryPath == "" {
+		// fall back to the config file, if set there
+		inventoryPath = viper.GetString("inventory.path")
+	}
+
+	checks := []doctorCheck{
+		{"log directory is writable", checkLogDir},
+		{"temp directory exists and allows executables", checkTempDir},
+		{"/proc is readable", checkProc},
+		{"/sys is readable", checkSys},
+		{"kernel version is parseable", checkKernel},
+		{"os-release is parseable", checkOSRelease},
+		{"inventory is valid", func() (string, error) { return checkInventory(inventoryPath) }},
+	}
+
+	failed := 0
+	for _, c := range checks {
+		detail, err := c.run()
+		if err != nil {
+			failed++
+			fmt.Printf("[FAIL] %s: %s\n", c.name, err)
+			continue
+		}
+
+		fmt.Printf("[PASS] %s: %s\n", c.name, detail)
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failed, len(checks))
+		os.Exit(1)
+	}
+}
+
+func checkLogDir() (string, error) {
+	if err := os.MkdirAll(doctorLogDir, 0755); err != nil {
+		return "", fmt.Errorf("Error creating log directory <%s>: %s", doctorLogDir, err)
+	}
+
+	f, err := os.CreateTemp(doctorLogDir, ".mh-doctor-*")
+	if err != nil {
+		return "", fmt.Errorf("Error writing to log directory <%s>: %s", doctorLogDir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return doctorLogDir, nil
+}
+
+func checkTempDir() (string, error) {
+	tmpDir := os.TempDir()
+	if _, err := os.Stat(tmpDir); err != nil {
+		return "", fmt.Errorf("Error checking temp directory <%s>: %s", tmpDir, err)
+	}
+
+	// make sure the temp dir isn't mounted `noexec`
+	f, err := os.CreateTemp(tmpDir, "mh-doctor-*.sh")
+	if err != nil {
+		return "", fmt.Errorf("Error writing to temp directory <%s>: %s", tmpDir, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("#!/bin/sh\nexit 0\n"); err != nil {
+		f.Close()
+		return "", fmt.Errorf("Error writing test script <%s>: %s", f.Name(), err)
+	}
+	f.Close()
+
+	if err := os.Chmod(f.Name(), 0700); err != nil {
+		return "", fmt.Errorf("Error making test script executable <%s>: %s", f.Name(), err)
+	}
+
+	if err := exec.Command(f.Name()).Run(); err != nil {
+		return "", fmt.Errorf("Error executing test script in temp directory <%s>: %s", tmpDir, err)
+	}
+
+	return tmpDir, nil
+}
+
+func checkProc() (string, error) {
+	fs, err := procfs.NewFS(doctorProcDir)
+	if err != nil {
+		return "", fmt.Errorf("Error opening procfs: %s", err)
+	}
+
+	if _, err := fs.CPUInfo(); err != nil {
+		return "", fmt.Errorf("Error reading cpu info: %s", err)
+	}
+
+	if _, err := fs.Meminfo(); err != nil {
+		return "", fmt.Errorf("Error reading memory info: %s", err)
+	}
+
+	if _, err := procfs.GetMounts(); err != nil {
+		return "", fmt.Errorf("Error reading mounts: %s", err)
+	}
+
+	return doctorProcDir, nil
+}
+
+func checkSys() (string, error) {
+	fs, err := blockdevice.NewFS(doctorProcDir, doctorSysDir)
+	if err != nil {
+		return "", fmt.Errorf("Error opening sysfs: %s", err)
+	}
+
+	devices, err := fs.SysBlockDevices()
+	if err != nil {
+		return "", fmt.Errorf("Error reading block devices: %s", err)
+	}
+
+	return fmt.Sprintf("%s (%d block devices)", doctorSysDir, len(devices)), nil
+}
+
+func checkKernel() (string, error) {
+	kernelInfo, err := kernelParser.GetKernelVersion()
+	if err != nil {
+		return "", fmt.Errorf("Error parsing kernel version: %s", err)
+	}
+
+	return kernelInfo.String(), nil
+}
+
+func checkOSRelease() (string, error) {
+	// distro.Path is relative, for use with an fs.FS
+	path := filepath.Join("/", distro.Path)
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Error opening os-release file <%s>: %s", path, err)
+	}
+	defer f.Close()
+
+	osRelease, err := distro.Parse(context.Background(), f)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing os-release file <%s>: %s", path, err)
+	}
+
+	return fmt.Sprintf("%s (%s)", path, osRelease["PRETTY_NAME"]), nil
+}
+
+func checkInventory(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("Inventory not defined, please set `--inventory.path` flag")
+	}
+
+	for _, dir := range inventoryDirectories {
+		info, err := os.Stat(filepath.Join(path, dir))
+		if err != nil {
+			return "", fmt.Errorf("Error checking inventory directory: %s", err)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("Inventory path <%s> is not a directory", filepath.Join(path, dir))
+		}
+	}
+
+	hostname := utils.GetHostname()
+	inv := inventory.NewInventory(path, hostname)
+	// the checklist is the output, so don't log inventory parsing noise
+	inv.Reload(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	return fmt.Sprintf("%s (host %s enrolled: %t)", path, hostname, inv.IsEnrolled()), nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+var (
+	enrollCmd = &cobra.Command{
+		Use:   "enroll",
+		Short: "Interactively enroll a host in the inventory",
+		Long: "Command to interactively enroll a host in the inventory. Prompts for a hostname, lists the" +
+			" roles/modules/groups available in the inventory, and writes a host entry with the chosen" +
+			" roles/modules (the same as `mh inventory host add --roles --modules`)",
+		Args: cobra.ExactArgs(0),
+		Run:  enroll,
+	}
+)
+
+func init() {
+	enrollCmdFlagSet := enrollCmd.Flags()
+	// not bound to viper, to avoid clobbering the `inventory` command's
+	// binding of the same flag
+	enrollCmdFlagSet.StringP("inventory.path", "i", "", "Path to mango configuration inventory")
+	rootCmd.AddCommand(enrollCmd)
+}
+
+// enrollPrompter reads answers to interactive prompts, falling back to the
+// default answer once input is exhausted.
+type enrollPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (p enrollPrompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	answer, err := p.in.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if err != nil {
+		fmt.Fprintln(p.out)
+	}
+	if answer == "" {
+		return def
+	}
+
+	return answer
+}
+
+func (p enrollPrompter) confirm(question string, def bool) bool {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+
+	switch strings.ToLower(p.ask(fmt.Sprintf("%s (%s)", question, choices), "")) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return def
+	}
+}
+
+// choose lists the options and returns the options chosen by the user, who
+// may answer with a comma separated list of option numbers and/or names.
+// Unknown names are kept (with a warning), so that components can be
+// referenced before they're created.
+func (p enrollPrompter) choose(kind string, options []string) []string {
+	if len(options) == 0 {
+		fmt.Fprintf(p.out, "\nNo %s found in the inventory\n", kind)
+		return nil
+	}
+
+	fmt.Fprintf(p.out, "\nAvailable %s:\n", kind)
+	for i, opt := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, opt)
+	}
+
+	var chosen []string
+	for _, answer := range strings.Split(p.ask(fmt.Sprintf("Choose %s (comma separated numbers or names, blank for none)", kind), ""), ",") {
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			continue
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n < 1 || n > len(options) {
+				fmt.Fprintf(p.out, "Ignoring invalid choice: %d\n", n)
+				continue
+			}
+			answer = options[n-1]
+		} else if !slices.Contains(options, answer) {
+			fmt.Fprintf(p.out, "Warning: %s is not in the inventory\n", answer)
+		}
+
+		if !slices.Contains(chosen, answer) {
+			chosen = append(chosen, answer)
+		}
+	}
+
+	return chosen
+}
+
+// suggestGroupGlob suggests a group glob for the hostname, by replacing any
+// trailing number (ie, `web-01`) with a wildcard. An empty string is returned
+// if the hostname doesn't end in a number.
+func suggestGroupGlob(hostname string) string {
+	prefix := strings.TrimRight(hostname, "0123456789")
+	if prefix == hostname || prefix == "" {
+		return ""
+	}
+
+	return prefix + "*"
+}
+
+func componentNames[T fmt.Stringer](components []T) []string {
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		names = append(names, filepath.Base(c.String()))
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func enroll(cmd *cobra.Command, args []string) {
+	logger := slog.Default().With("component", "enroll")
+	inventoryPath, _ := cmd.Flags().GetString("inventory.path")
+	if inventoryPath == "" {
+		// fall back to the config file, if set there
+		inventoryPath = viper.GetString("inventory.path")
+	}
+	if inventoryPath == "" {
+		logger.Error("Inventory not defined, please set `--inventory.path` flag")
+		os.Exit(1)
+	}
+
+	p := enrollPrompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	hostname := p.ask("Hostname to enroll", utils.GetHostname())
+
+	inv := inventory.NewInventory(inventoryPath, hostname)
+	// prompts are the output, so don't log inventory parsing noise
+	inv.Reload(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, found := inv.GetHost(hostname); found {
+		fmt.Printf("\nHost %s already exists in the inventory\n", hostname)
+		if !p.confirm("Overwrite its roles/modules?", false) {
+			return
+		}
+	}
+
+	var matched []string
+	for _, g := range inv.GetGroupsForHost(hostname) {
+		matched = append(matched, g.String())
+	}
+	fmt.Printf("\nGroups: %s\n", strings.Join(componentNames(inv.GetGroups()), ", "))
+	if len(matched) > 0 {
+		slices.Sort(matched)
+		fmt.Printf("Host %s is already a member of groups: %s\n", hostname, strings.Join(matched, ", "))
+	} else if glob := suggestGroupGlob(hostname); glob != "" {
+		fmt.Printf("Host %s isn't a member of any groups. To manage similarly named hosts together, consider adding a group with a glob pattern of %q\n", hostname, glob)
+	}
+
+	roles := p.choose("roles", componentNames(inv.GetRoles()))
+	modules := p.choose("modules", componentNames(inv.GetModules()))
+
+	fmt.Printf("\nHost: %s\nRoles: %s\nModules: %s\n", hostname, strings.Join(roles, ", "), strings.Join(modules, ", "))
+	if !p.confirm("Write host to inventory?", true) {
+		return
+	}
+
+	hLogger := logger.With("host", hostname)
+	warnMissingComponents(hLogger, inventoryPath, "roles", roles)
+	warnMissingComponents(hLogger, inventoryPath, "modules", modules)
+	addHost(hLogger, inventoryPath, hostname, roles, modules)
+
+	fmt.Printf("Wrote host %s to %s\n", hostname, hostPath(inventoryPath, hostname))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/internal/manager"
+	"github.com/tjhop/mango/internal/shell"
+)
+
+const (
+	exportFormatAnsible     = "ansible"
+	exportFormatAnsibleYAML = "ansible-yaml"
+)
+
+var (
+	invExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the inventory's hosts and groups to another tool's inventory format",
+		Long: "Command to export the inventory's hosts, groups, group membership, and variables to another tool's inventory format." +
+			" Only hosts defined in the inventory are exported, and group membership is determined by matching those hosts against each group's globs/patterns." +
+			" Variables files are rendered and sourced using the local system's metadata." +
+			" Roles and modules don't map cleanly to other tools' inventories and are omitted.",
+		Args: cobra.ExactArgs(0),
+		Run:  inventoryExport,
+	}
+)
+
+func init() {
+	invExportCmdFlagSet := invExportCmd.Flags()
+	// not bound to viper, to avoid clobbering other commands' bindings of
+	// similarly named flags
+	invExportCmdFlagSet.String("format", exportFormatAnsible, "Format to export the inventory as, may be one of: [ansible (INI), ansible-yaml]")
+	inventoryCmd.AddCommand(invExportCmd)
+}
+
+// exportGroup is a group of hosts and the group's variables, in a generic form
+// that can be rendered to the supported export formats.
+type exportGroup struct {
+	name  string
+	hosts []string
+	vars  map[string]string
+}
+
+// exportInventory is the inventory's hosts/groups in a generic form that can
+// be rendered to the supported export formats.
+type exportInventory struct {
+	hosts    []string
+	hostVars map[string]map[string]string
+	groups   []exportGroup
+}
+
+func inventoryExport(cmd *cobra.Command, args []string) {
+	logger := slog.Default().With("component", "export")
+	format, _ := cmd.Flags().GetString("format")
+
+	inv := loadInventory()
+	exp := buildExportInventory(context.Background(), logger, inv)
+
+	var err error
+	switch format {
+	case exportFormatAnsible:
+		err = writeAnsibleINI(os.Stdout, exp)
+	case exportFormatAnsibleYAML:
+		err = writeAnsibleYAML(os.Stdout, exp)
+	default:
+		err = fmt.Errorf("Unsupported export format <%s>", format)
+	}
+
+	if err != nil {
+		logger.Error("Error exporting inventory", "err", err)
+		os.Exit(1)
+	}
+}
+
+func buildExportInventory(ctx context.Context, logger *slog.Logger, inv *inventory.Inventory) exportInventory {
+	mgr := manager.NewManager(inv.GetHostname())
+	sourceVars := func(paths []string) map[string]string {
+		if len(paths) == 0 {
+			return nil
+		}
+
+		return shell.MakeVariableMap(mgr.ReloadVariables(ctx, logger, paths, nil, nil))
+	}
+
+	exp := exportInventory{hostVars: make(map[string]map[string]string)}
+	for _, h := range inv.GetHosts() {
+		exp.hosts = append(exp.hosts, h.String())
+		if vars := sourceVars(h.Variables()); len(vars) > 0 {
+			exp.hostVars[h.String()] = vars
+		}
+	}
+	slices.Sort(exp.hosts)
+
+	for _, g := range inv.GetGroups() {
+		group := exportGroup{
+			name: g.String(),
+			vars: sourceVars(inv.GetVariablesForGroup(g.String())),
+		}
+
+		for _, host := range exp.hosts {
+			if g.IsHostEnrolled(host) {
+				group.hosts = append(group.hosts, host)
+			}
+		}
+
+		exp.groups = append(exp.groups, group)
+	}
+	slices.SortFunc(exp.groups, func(a, b exportGroup) int { return strings.Compare(a.name, b.name) })
+
+	return exp
+}
+
+// formatINIVars formats the variables as space separated `key="value"` pairs,
+// sorted by key.
+func formatINIVars(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, strconv.Quote(vars[k])))
+	}
+
+	return pairs
+}
+
+func writeAnsibleINI(w io.Writer, exp exportInventory) error {
+	var sb strings.Builder
+
+	sb.WriteString("[all]\n")
+	for _, host := range exp.hosts {
+		sb.WriteString(strings.Join(append([]string{host}, formatINIVars(exp.hostVars[host])...), " ") + "\n")
+	}
+
+	for _, group := range exp.groups {
+		fmt.Fprintf(&sb, "\n[%s]\n", group.name)
+		for _, host := range group.hosts {
+			sb.WriteString(host + "\n")
+		}
+
+		if len(group.vars) > 0 {
+			fmt.Fprintf(&sb, "\n[%s:vars]\n", group.name)
+			for _, pair := range formatINIVars(group.vars) {
+				sb.WriteString(pair + "\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type ansibleYAMLGroup struct {
+	Hosts    map[string]map[string]string `yaml:"hosts,omitempty"`
+	Vars     map[string]string            `yaml:"vars,omitempty"`
+	Children map[string]ansibleYAMLGroup  `yaml:"children,omitempty"`
+}
+
+func writeAnsibleYAML(w io.Writer, exp exportInventory) error {
+	all := ansibleYAMLGroup{
+		Hosts:    make(map[string]map[string]string),
+		Children: make(map[string]ansibleYAMLGroup),
+	}
+
+	for _, host := range exp.hosts {
+		all.Hosts[host] = exp.hostVars[host]
+	}
+
+	for _, group := range exp.groups {
+		g := ansibleYAMLGroup{
+			Hosts: make(map[string]map[string]string),
+			Vars:  group.vars,
+		}
+		for _, host := range group.hosts {
+			// host vars are already set under `all`
+			g.Hosts[host] = nil
+		}
+
+		all.Children[group.name] = g
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]ansibleYAMLGroup{"all": all}); err != nil {
+		return fmt.Errorf("Error encoding inventory as YAML: %s", err)
+	}
+
+	return enc.Close()
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 
 	"github.com/tjhop/mango/internal/inventory"
@@ -38,6 +39,23 @@ var (
 		Run:     groupDelete,
 	}
 
+	groupEnableCmd = &cobra.Command{
+		Use:   "enable",
+		Short: "Enable the group with the provided name",
+		Long:  "Command to re-enable a disabled group by removing its `disabled` marker file",
+		Args:  cobra.ExactArgs(1),
+		Run:   groupSetDisabled(false),
+	}
+
+	groupDisableCmd = &cobra.Command{
+		Use:   "disable",
+		Short: "Disable the group with the provided name, without deleting it",
+		Long: "Command to temporarily disable a group by adding a `disabled` marker file to its directory," +
+			" which causes mango to skip it while parsing the inventory",
+		Args: cobra.ExactArgs(1),
+		Run:  groupSetDisabled(true),
+	}
+
 	groupListCmd = &cobra.Command{
 		Use:     "list",
 		Aliases: listCmdAliases,
@@ -52,6 +70,8 @@ func init() {
 	inventoryCmd.AddCommand(groupCmd)
 	groupCmd.AddCommand(groupAddCmd)
 	groupCmd.AddCommand(groupDeleteCmd)
+	groupCmd.AddCommand(groupEnableCmd)
+	groupCmd.AddCommand(groupDisableCmd)
 	groupCmd.AddCommand(groupListCmd)
 }
 
@@ -111,3 +131,15 @@ func groupList(cmd *cobra.Command, args []string) {
 		fmt.Println(g.String())
 	}
 }
+
+func groupSetDisabled(disabled bool) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		groupName := args[0]
+		logger := slog.Default().With("component", "group", "group", groupName)
+
+		if err := inventorySetDisabled(filepath.Join(viper.GetString("inventory.path"), "groups", groupName), disabled); err != nil {
+			logger.Error("Error toggling group", "err", err)
+			os.Exit(1)
+		}
+	}
+}
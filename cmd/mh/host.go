@@ -3,12 +3,15 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/pkg/utils"
 )
 
 var (
@@ -24,8 +27,9 @@ var (
 		Aliases: addCmdAliases,
 		Short:   "Create an empty host with the provided name",
 		Long: "Command to add a new host by adding a new directory with the given" +
-			" name and creating empty host files to bootstrap",
-		Args: cobra.ExactArgs(1),
+			" name and creating empty host files to bootstrap. Multiple hosts can be" +
+			" added at once from a newline delimited file of hostnames with `--from-file`",
+		Args: cobra.MaximumNArgs(1),
 		Run:  hostAdd,
 	}
 
@@ -38,6 +42,23 @@ var (
 		Run:     hostDelete,
 	}
 
+	hostEnableCmd = &cobra.Command{
+		Use:   "enable",
+		Short: "Enable the host with the provided name",
+		Long:  "Command to re-enable a disabled host by removing its `disabled` marker file",
+		Args:  cobra.ExactArgs(1),
+		Run:   hostSetDisabled(false),
+	}
+
+	hostDisableCmd = &cobra.Command{
+		Use:   "disable",
+		Short: "Disable the host with the provided name, without deleting it",
+		Long: "Command to temporarily disable a host by adding a `disabled` marker file to its directory," +
+			" which causes mango to skip it while parsing the inventory",
+		Args: cobra.ExactArgs(1),
+		Run:  hostSetDisabled(true),
+	}
+
 	hostListCmd = &cobra.Command{
 		Use:     "list",
 		Aliases: listCmdAliases,
@@ -49,18 +70,88 @@ var (
 )
 
 func init() {
+	hostAddCmdFlagSet := hostAddCmd.Flags()
+	// not bound to viper, to avoid clobbering other commands' bindings of
+	// similarly named flags
+	hostAddCmdFlagSet.String("from-file", "", "Path to a newline delimited file of hostnames to add. Hosts that already exist are skipped")
+	hostAddCmdFlagSet.StringSlice("roles", nil, "Comma separated list of roles to write to each added host's roles file")
+	hostAddCmdFlagSet.StringSlice("modules", nil, "Comma separated list of modules to write to each added host's modules file")
+
 	inventoryCmd.AddCommand(hostCmd)
 	hostCmd.AddCommand(hostAddCmd)
 	hostCmd.AddCommand(hostDeleteCmd)
+	hostCmd.AddCommand(hostEnableCmd)
+	hostCmd.AddCommand(hostDisableCmd)
 	hostCmd.AddCommand(hostListCmd)
 }
 
 func hostAdd(cmd *cobra.Command, args []string) {
-	hostName := args[0]
-	logger := slog.Default().With("component", "host", "host", hostName)
+	logger := slog.Default().With("component", "host")
+	inventoryPath := viper.GetString("inventory.path")
+	fromFile, _ := cmd.Flags().GetString("from-file")
+	roles, _ := cmd.Flags().GetStringSlice("roles")
+	modules, _ := cmd.Flags().GetStringSlice("modules")
+	warnMissingComponents(logger, inventoryPath, "roles", roles)
+	warnMissingComponents(logger, inventoryPath, "modules", modules)
+
+	if fromFile == "" {
+		if len(args) != 1 {
+			logger.Error("Error adding host, please provide a hostname or `--from-file`")
+			os.Exit(1)
+		}
+
+		addHost(logger.With("host", args[0]), inventoryPath, args[0], roles, modules)
+		return
+	}
+
+	seen := make(map[string]struct{})
+	for line := range utils.ReadFileLines(fromFile) {
+		if line.Err != nil {
+			logger.Error("Error reading hostnames file", "err", line.Err, "file", fromFile)
+			os.Exit(1)
+		}
+
+		hostName := strings.TrimSpace(line.Text)
+		if hostName == "" || strings.HasPrefix(hostName, "#") {
+			continue
+		}
+
+		hLogger := logger.With("host", hostName)
+		if _, found := seen[hostName]; found {
+			hLogger.Info("Skipping duplicate host in hostnames file")
+			continue
+		}
+		seen[hostName] = struct{}{}
+
+		if _, err := os.Stat(hostPath(inventoryPath, hostName)); err == nil {
+			hLogger.Info("Skipping host that already exists in the inventory")
+			continue
+		}
+
+		addHost(hLogger, inventoryPath, hostName, roles, modules)
+	}
+}
 
-	hostDir := filepath.Join(viper.GetString("inventory.path"), "hosts")
-	hostPath := filepath.Join(hostDir, hostName)
+func hostPath(inventoryPath, hostName string) string {
+	return filepath.Join(inventoryPath, "hosts", hostName)
+}
+
+// warnMissingComponents logs a warning for each of the named components (ie,
+// roles or modules) that doesn't exist in the inventory's component directory.
+func warnMissingComponents(logger *slog.Logger, inventoryPath, component string, names []string) {
+	for _, name := range names {
+		path := filepath.Join(inventoryPath, component, name)
+		if _, err := os.Stat(path); err != nil {
+			logger.Warn("Referenced inventory component does not exist", "kind", component, "name", name, "path", path)
+		}
+	}
+}
+
+// addHost creates the directory and empty files for the named host, and
+// writes the provided roles/modules (if any) to the host's roles/modules
+// files.
+func addHost(logger *slog.Logger, inventoryPath, hostName string, roles, modules []string) {
+	hostPath := hostPath(inventoryPath, hostName)
 
 	if err := inventoryAddDir(hostPath); err != nil {
 		logger.Warn("Error initializing host", "err", err)
@@ -83,16 +174,26 @@ func hostAdd(cmd *cobra.Command, args []string) {
 			logger.Debug("Created host directory", "dir", dir)
 		}
 	}
+
+	for hFile, lines := range map[string][]string{"roles": roles, "modules": modules} {
+		if len(lines) == 0 {
+			continue
+		}
+
+		file := filepath.Join(hostPath, hFile)
+		if err := inventoryWriteLines(file, lines); err != nil {
+			logger.Warn("Error writing host file", "err", err, "file", file)
+		} else {
+			logger.Debug("Wrote host file", "file", file, hFile, lines)
+		}
+	}
 }
 
 func hostDelete(cmd *cobra.Command, args []string) {
 	hostName := args[0]
 	logger := slog.Default().With("component", "host", "host", hostName)
 
-	hostDir := filepath.Join(viper.GetString("inventory.path"), "hosts")
-	hostPath := filepath.Join(hostDir, hostName)
-
-	if err := inventoryRemoveAll(hostPath); err != nil {
+	if err := inventoryRemoveAll(hostPath(viper.GetString("inventory.path"), hostName)); err != nil {
 		logger.Warn("Error deleting host", "err", err)
 	}
 }
@@ -112,3 +213,15 @@ func hostList(cmd *cobra.Command, args []string) {
 		fmt.Println(h.String())
 	}
 }
+
+func hostSetDisabled(disabled bool) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		hostName := args[0]
+		logger := slog.Default().With("component", "host", "host", hostName)
+
+		if err := inventorySetDisabled(hostPath(viper.GetString("inventory.path"), hostName), disabled); err != nil {
+			logger.Error("Error toggling host", "err", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/pkg/utils"
 )
 
 var (
@@ -36,6 +37,9 @@ func loadInventory() *inventory.Inventory {
 	logger := slog.Default().With("component", "inventory")
 	inventoryPath := viper.GetString("inventory.path")
 	hostname := viper.GetString("hostname")
+	if hostname == "" {
+		hostname = utils.GetHostname()
+	}
 
 	inv := inventory.NewInventory(inventoryPath, hostname)
 	logger.Debug("Created new inventory", "inventory_path", inventoryPath, "hostname", hostname)
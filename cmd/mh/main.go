@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/tjhop/mango/internal/config"
 	"github.com/tjhop/mango/internal/version"
 
 	"github.com/spf13/cobra"
@@ -26,12 +27,46 @@ var (
 
 func init() {
 	rootCmdFlagSet := rootCmd.PersistentFlags()
-	rootCmdFlagSet.StringP("logging.level", "l", "info", "Logging level may be one of: [debug, info, warning, error]")
+	rootCmdFlagSet.String("config.file", "", "Path to a config file to read settings from, shared with mango. Flags take precedence over config file settings [default 'mango.yaml' in /etc/mango, $HOME/mango, or the working directory, if present]")
+	rootCmdFlagSet.StringP("logging.level", "l", "info", "Logging level may be one of: [debug, info, warn, error], 'warning' is accepted as an alias for 'warn'")
 	rootCmdFlagSet.String("logging.output", "logfmt", "Logging format may be one of: [logfmt, json]")
 	if err := viper.BindPFlags(rootCmdFlagSet); err != nil {
 		panic(fmt.Errorf("Error binding flags for command <%s>: %s", "mh", err))
 	}
 
+	// the version flag is handled by cobra before any initializers run, so
+	// the output format is checked when the version template is rendered
+	rootCmd.Flags().String("output", "text", "Output format for '--version', may be one of: [text, json]")
+	cobra.AddTemplateFunc("versionInfo", func() string {
+		if output, _ := rootCmd.Flags().GetString("output"); strings.ToLower(output) == "json" {
+			info, err := version.PrintJSON()
+			if err != nil {
+				return err.Error() + "\n"
+			}
+			return info
+		}
+
+		return version.Print(os.Args[0])
+	})
+	rootCmd.SetVersionTemplate("{{ versionInfo }}")
+	// define the version flag up front (rather than lazily when executing),
+	// so that `--version --output json` parses `--version` as a bool flag
+	rootCmd.InitDefaultVersionFlag()
+
+	// config file and logging settings can only be read once flags have
+	// been parsed
+	cobra.OnInitialize(initConfig)
+}
+
+// initConfig reads the config file (if any) and sets up logging.
+func initConfig() {
+	config.AutomaticEnv()
+	configFile, err := config.ReadInConfig(viper.GetString("config.file"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	logHandlerOpts := &slog.HandlerOptions{
 		Level:     logLevel,
 		AddSource: true,
@@ -48,14 +83,17 @@ func init() {
 		},
 	}
 
-	// parse log output format from flag, create root logger with default configs
+	// parse log output format from flag, create root logger with default
+	// configs. logs go to stderr, so that command output on stdout can be
+	// piped to other tools
 	var logger *slog.Logger
 	logOutputFormat := strings.ToLower(viper.GetString("logging.output"))
 	if logOutputFormat == "json" {
-		logger = slog.New(slog.NewJSONHandler(os.Stdout, logHandlerOpts))
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, logHandlerOpts))
 	} else {
-		logger = slog.New(slog.NewTextHandler(os.Stdout, logHandlerOpts))
+		logger = slog.New(slog.NewTextHandler(os.Stderr, logHandlerOpts))
 	}
+	slog.SetDefault(logger)
 
 	// parse log level from flag
 	logLevelFlagVal := strings.ToLower(viper.GetString("logging.level"))
@@ -64,7 +102,7 @@ func init() {
 		logLevel.Set(slog.LevelInfo)
 		logger.Warn("Log level flag not set, defaulting to <info> level")
 	case "info": // default is info, we're good
-	case "warn":
+	case "warn", "warning":
 		logLevel.Set(slog.LevelWarn)
 	case "debug":
 		logLevel.Set(slog.LevelDebug)
@@ -77,6 +115,10 @@ func init() {
 			slog.String("log_level", logLevelFlagVal),
 		)
 	}
+
+	if configFile != "" {
+		logger.Debug("Read settings from config file", "path", configFile)
+	}
 }
 
 func main() {
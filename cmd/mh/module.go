@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
 	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/internal/manager"
+	"github.com/tjhop/mango/pkg/utils"
 )
 
 var (
@@ -37,9 +44,44 @@ var (
 		Run:     moduleDelete,
 	}
 
+	modEnableCmd = &cobra.Command{
+		Use:   "enable",
+		Short: "Enable the module with the provided name",
+		Long:  "Command to re-enable a disabled module by removing its `disabled` marker file",
+		Args:  cobra.ExactArgs(1),
+		Run:   moduleSetDisabled(false),
+	}
+
+	modDisableCmd = &cobra.Command{
+		Use:   "disable",
+		Short: "Disable the module with the provided name, without deleting it",
+		Long: "Command to temporarily disable a module by adding a `disabled` marker file to its directory," +
+			" which causes mango to skip it while parsing the inventory",
+		Args: cobra.ExactArgs(1),
+		Run:  moduleSetDisabled(true),
+	}
+
+	modRenderCmd = &cobra.Command{
+		Use:   "render",
+		Short: "Render the templated script for the module with the provided name",
+		Long: "Command to render a module's templated script for the provided host, without running it." +
+			" Run specific template metadata (run ID, enrollment status, etc) is populated the same way as for a real run",
+		Args: cobra.ExactArgs(1),
+		Run:  moduleRender,
+	}
+
+	modShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Show details about the module with the provided name",
+		Long:  "Command to show a module's path, files, and descriptive metadata from its `meta` file, if present",
+		Args:  cobra.ExactArgs(1),
+		Run:   moduleShow,
+	}
+
 	modListCmd = &cobra.Command{
-		Use:     "list",
-		Aliases: listCmdAliases,
+		Use: "list",
+		// `show` is its own command for modules
+		Aliases: []string{"print", "ls"},
 		Short:   "List modules in the inventory",
 		Long:    "Command to list modules in the inventory",
 		Args:    cobra.ExactArgs(0),
@@ -51,13 +93,28 @@ func init() {
 	inventoryCmd.AddCommand(moduleCmd)
 	moduleCmd.AddCommand(modAddCmd)
 	moduleCmd.AddCommand(modDeleteCmd)
+	moduleCmd.AddCommand(modEnableCmd)
+	moduleCmd.AddCommand(modDisableCmd)
 
 	modListCmdFlagSet := inventoryCmd.PersistentFlags()
 	modListCmdFlagSet.Bool("enrolled-only", false, "Only return modules that the provided host is enrolled for")
 	if err := viper.BindPFlags(modListCmdFlagSet); err != nil {
 		panic(fmt.Errorf("Error binding flags for command <%s>: %s", "inventory", err))
 	}
+	// not bound to viper, to avoid clobbering other commands' bindings of
+	// similarly named flags
+	modListCmd.Flags().String("format", "text", "Format to list modules in, may be one of: [text, json]")
 	moduleCmd.AddCommand(modListCmd)
+
+	modShowCmd.Flags().String("format", "text", "Format to show the module in, may be one of: [text, json]")
+	moduleCmd.AddCommand(modShowCmd)
+
+	modRenderCmdFlagSet := modRenderCmd.Flags()
+	modRenderCmdFlagSet.String("script", "apply", "Module script to render, may be one of: [apply, test]")
+	if err := viper.BindPFlags(modRenderCmdFlagSet); err != nil {
+		panic(fmt.Errorf("Error binding flags for command <%s>: %s", "module render", err))
+	}
+	moduleCmd.AddCommand(modRenderCmd)
 }
 
 func moduleAdd(cmd *cobra.Command, args []string) {
@@ -112,7 +169,112 @@ func moduleList(cmd *cobra.Command, args []string) {
 		modules = inv.GetModules()
 	}
 
+	if format, _ := cmd.Flags().GetString("format"); strings.ToLower(format) == "json" {
+		infos := make([]moduleInfo, 0, len(modules))
+		for _, mod := range modules {
+			infos = append(infos, newModuleInfo(mod))
+		}
+		printModuleJSON(infos)
+		return
+	}
+
 	for _, mod := range modules {
 		fmt.Println(mod.String())
 	}
 }
+
+// moduleInfo is the summary of a module shown by `module show` and
+// `module list --format json`.
+type moduleInfo struct {
+	Name  string                `json:"name"`
+	Path  string                `json:"path"`
+	State string                `json:"state"`
+	Files []string              `json:"files"`
+	Meta  *inventory.ModuleMeta `json:"meta,omitempty"`
+}
+
+func newModuleInfo(mod inventory.Module) moduleInfo {
+	info := moduleInfo{
+		Name:  mod.ID,
+		Path:  mod.Path,
+		State: mod.State,
+		Files: []string{},
+		Meta:  mod.Meta,
+	}
+
+	// errors reading the module were already logged while parsing the
+	// inventory
+	entries, _ := os.ReadDir(mod.Path)
+	for _, e := range entries {
+		if !utils.IsHidden(e.Name()) {
+			info.Files = append(info.Files, e.Name())
+		}
+	}
+
+	return info
+}
+
+func printModuleJSON(v any) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		slog.Default().Error("Error encoding modules as JSON", "err", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(out))
+}
+
+func moduleShow(cmd *cobra.Command, args []string) {
+	modName := args[0]
+	logger := slog.Default().With("component", "module", "module", modName)
+	inv := loadInventory()
+
+	mod, found := inv.GetModule(modName)
+	if !found {
+		logger.Error("Module not found in inventory")
+		os.Exit(1)
+	}
+	info := newModuleInfo(mod)
+
+	if format, _ := cmd.Flags().GetString("format"); strings.ToLower(format) == "json" {
+		printModuleJSON(info)
+		return
+	}
+
+	fmt.Printf("Module: %s\n", info.Name)
+	fmt.Printf("Path: %s\n", info.Path)
+	fmt.Printf("State: %s\n", info.State)
+	if info.Meta != nil {
+		fmt.Printf("Description: %s\n", info.Meta.Description)
+		fmt.Printf("Author: %s\n", info.Meta.Author)
+		fmt.Printf("Version: %s\n", info.Meta.Version)
+	}
+	fmt.Printf("Files: %s\n", strings.Join(info.Files, ", "))
+}
+
+func moduleRender(cmd *cobra.Command, args []string) {
+	modName := args[0]
+	logger := slog.Default().With("component", "module", "module", modName)
+	inv := loadInventory()
+	mgr := manager.NewManager(inv.GetHostname())
+
+	rendered, err := mgr.RenderModuleScript(context.Background(), logger, inv, modName, viper.GetString("script"))
+	if err != nil {
+		logger.Error("Error rendering module script", "err", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(rendered)
+}
+
+func moduleSetDisabled(disabled bool) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		modName := args[0]
+		logger := slog.Default().With("component", "module", "module", modName)
+
+		if err := inventorySetDisabled(filepath.Join(viper.GetString("inventory.path"), "modules", modName), disabled); err != nil {
+			logger.Error("Error toggling module", "err", err)
+			os.Exit(1)
+		}
+	}
+}
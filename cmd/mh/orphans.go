@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	invOrphansCmd = &cobra.Command{
+		Use:   "orphans",
+		Short: "List unused modules, roles, and groups in the inventory",
+		Long: "Command to list modules that aren't referenced by any host/role/group, roles that aren't referenced by any host/group," +
+			" and groups whose globs/patterns match none of the hosts defined in the inventory, to help prune dead inventory." +
+			" Note that groups are only matched against hosts defined in the inventory, not the local system",
+		Args: cobra.ExactArgs(0),
+		Run:  inventoryOrphans,
+	}
+)
+
+func init() {
+	inventoryCmd.AddCommand(invOrphansCmd)
+}
+
+func printOrphans(heading string, names []string) {
+	fmt.Printf("%s:\n", heading)
+	if len(names) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+}
+
+func inventoryOrphans(cmd *cobra.Command, args []string) {
+	inv := loadInventory()
+
+	printOrphans("Modules not referenced by any host, role, or group", componentNames(inv.GetOrphanedModules()))
+	fmt.Println()
+	printOrphans("Roles not referenced by any host or group", componentNames(inv.GetOrphanedRoles()))
+	fmt.Println()
+	printOrphans("Groups that match no host in the inventory", componentNames(inv.GetEmptyGroups()))
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -39,6 +41,33 @@ var (
 		Run:     roleDelete,
 	}
 
+	roleEnableCmd = &cobra.Command{
+		Use:   "enable",
+		Short: "Enable the role with the provided name",
+		Long:  "Command to re-enable a disabled role by removing its `disabled` marker file",
+		Args:  cobra.ExactArgs(1),
+		Run:   roleSetDisabled(false),
+	}
+
+	roleDisableCmd = &cobra.Command{
+		Use:   "disable",
+		Short: "Disable the role with the provided name, without deleting it",
+		Long: "Command to temporarily disable a role by adding a `disabled` marker file to its directory," +
+			" which causes mango to skip it while parsing the inventory",
+		Args: cobra.ExactArgs(1),
+		Run:  roleSetDisabled(true),
+	}
+
+	roleApplyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Apply only the modules of the role with the provided name to the host",
+		Long: "Command to perform a one-shot converge of only the modules in the provided role, in dependency order," +
+			" ignoring the host's other modules, directives, and host scripts. Useful for targeted remediation." +
+			" Exits non-zero if the role isn't assigned to the host, or if any module failed",
+		Args: cobra.ExactArgs(1),
+		Run:  roleApply,
+	}
+
 	roleListCmd = &cobra.Command{
 		Use:     "list",
 		Aliases: listCmdAliases,
@@ -53,7 +82,14 @@ func init() {
 	inventoryCmd.AddCommand(roleCmd)
 	roleCmd.AddCommand(roleAddCmd)
 	roleCmd.AddCommand(roleDeleteCmd)
+	roleCmd.AddCommand(roleEnableCmd)
+	roleCmd.AddCommand(roleDisableCmd)
 	roleCmd.AddCommand(roleListCmd)
+
+	// not bound to viper, to avoid clobbering the `run` command's binding
+	// of the same flag
+	roleApplyCmd.Flags().Bool("stream", true, "Stream the stdout/stderr of scripts to the terminal as they run, in addition to the script log files")
+	roleCmd.AddCommand(roleApplyCmd)
 }
 
 func roleAdd(cmd *cobra.Command, args []string) {
@@ -104,3 +140,55 @@ func roleList(cmd *cobra.Command, args []string) {
 		fmt.Println(g.String())
 	}
 }
+
+func roleApply(cmd *cobra.Command, args []string) {
+	roleName := args[0]
+	logger := slog.Default().With("component", "role", "role", roleName)
+	inv := loadInventory()
+
+	assigned := false
+	for _, r := range inv.GetRolesForSelf() {
+		if filepath.Base(r.String()) == roleName {
+			assigned = true
+			break
+		}
+	}
+	if !assigned {
+		logger.Error("Role is not assigned to host", "hostname", inv.GetHostname())
+		os.Exit(1)
+	}
+
+	var modules []string
+	for _, mod := range inv.GetModulesForRole(roleName) {
+		modules = append(modules, mod.ID)
+	}
+	if len(modules) == 0 {
+		logger.Info("Role has no modules to apply")
+		return
+	}
+
+	cleanup, err := prepareRun()
+	if err != nil {
+		logger.Error("Error preparing run", "err", err)
+		os.Exit(1)
+	}
+
+	stream, _ := cmd.Flags().GetBool("stream")
+	failed := converge(context.Background(), logger, inv, modules, stream)
+	cleanup()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func roleSetDisabled(disabled bool) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		roleName := args[0]
+		logger := slog.Default().With("component", "role", "role", roleName)
+
+		if err := inventorySetDisabled(filepath.Join(viper.GetString("inventory.path"), "roles", roleName), disabled); err != nil {
+			logger.Error("Error toggling role", "err", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/internal/manager"
+	"github.com/tjhop/mango/internal/shell"
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+var (
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Perform a one-shot converge of a host",
+		Long: "Command to perform a complete one-shot converge of the provided host, the same way the mango daemon would," +
+			" without needing the daemon to be running. Reloads the inventory, runs all directives/modules synchronously," +
+			" streams progress to the terminal, and exits non-zero if any directive or module failed",
+		Args: cobra.ExactArgs(0),
+		Run:  run,
+	}
+)
+
+func init() {
+	runCmdFlagSet := runCmd.Flags()
+	// not bound to viper, to avoid clobbering the `inventory` command's
+	// binding of the same flags
+	runCmdFlagSet.StringP("inventory.path", "i", "", "Path to mango configuration inventory to converge from")
+	runCmdFlagSet.String("hostname", "", "Hostname to converge as [default is system hostname]")
+	runCmdFlagSet.Bool("stream", true, "Stream the stdout/stderr of scripts to the terminal as they run, in addition to the script log files")
+	rootCmd.AddCommand(runCmd)
+}
+
+func run(cmd *cobra.Command, args []string) {
+	logger := slog.Default().With("component", "run")
+	inventoryPath, _ := cmd.Flags().GetString("inventory.path")
+	if inventoryPath == "" {
+		// fall back to the config file, if set there
+		inventoryPath = viper.GetString("inventory.path")
+	}
+	if inventoryPath == "" {
+		logger.Error("Inventory not defined, please set `--inventory.path` flag")
+		os.Exit(1)
+	}
+	hostname, _ := cmd.Flags().GetString("hostname")
+	if hostname == "" {
+		hostname = utils.GetHostname()
+	}
+
+	cleanup, err := prepareRun()
+	if err != nil {
+		logger.Error("Error preparing run", "err", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	inv := inventory.NewInventory(inventoryPath, hostname)
+	inv.Reload(ctx, logger)
+
+	stream, _ := cmd.Flags().GetBool("stream")
+	failed := converge(ctx, logger, inv, nil, stream)
+	cleanup()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// prepareRun sets up the log and temporary directories for a one-shot run.
+// The returned function removes the temporary directory, and should be called
+// once the run finishes.
+func prepareRun() (func(), error) {
+	// keep in sync with the paths that mango uses, so that script logs
+	// from a one-shot converge end up alongside the daemon's
+	if err := os.MkdirAll(doctorLogDir, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to create log directory %s: %v", doctorLogDir, err)
+	}
+	viper.Set("mango.log-dir", doctorLogDir)
+	dir, err := os.MkdirTemp(viper.GetString("mango.temp-dir"), "mango")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary directory for run: %v", err)
+	}
+	viper.Set("mango.temp-dir", dir)
+	// keep in sync with mango's default
+	viper.SetDefault("manager.stderr-tail-lines", 10)
+
+	return func() { os.RemoveAll(dir) }, nil
+}
+
+// converge runs a converge of the host and prints its progress, returning
+// whether or not the converge failed. If modules is non-nil, only the provided
+// modules are run.
+func converge(ctx context.Context, logger *slog.Logger, inv *inventory.Inventory, modules []string, stream bool) bool {
+	mgr := manager.NewManager(inv.GetHostname())
+	events, unsubscribe := mgr.Subscribe()
+	printer := &runPrinter{events: events}
+	if stream {
+		mgr.SetRunOptions(shell.RunOptions{
+			Stdout: printer.writer(os.Stdout),
+			Stderr: printer.writer(os.Stderr),
+		})
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				printer.drain()
+			}
+		}
+	}()
+
+	var (
+		report *manager.RunReport
+		err    error
+	)
+	if modules != nil {
+		report, err = mgr.ConvergeModules(ctx, logger, inv, modules)
+	} else {
+		report, err = mgr.Converge(ctx, logger, inv)
+	}
+	close(stop)
+	<-done
+	unsubscribe()
+	printer.drain()
+	if err != nil {
+		logger.Error("Error running converge", "err", err)
+		return true
+	}
+
+	failed := 0
+	for _, res := range append(report.Directives, report.Modules...) {
+		if !res.Success {
+			failed++
+		}
+	}
+
+	fmt.Printf("\nRun %s finished in %.2fs\n", report.RunID, report.Duration)
+	if !report.Success {
+		fmt.Printf("%d of %d directives/modules failed\n", failed, len(report.Directives)+len(report.Modules))
+	}
+
+	return !report.Success
+}
+
+// runPrinter prints converge progress events. Events are only received while
+// holding the lock, and pending events are printed before any streamed script
+// output, so that progress and script output are printed in order.
+type runPrinter struct {
+	mu     sync.Mutex
+	events <-chan manager.Event
+}
+
+// drain prints all of the pending events.
+func (p *runPrinter) drain() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.drainLocked()
+}
+
+func (p *runPrinter) drainLocked() {
+	for {
+		select {
+		case e, ok := <-p.events:
+			if !ok {
+				return
+			}
+			printRunEvent(e)
+		default:
+			return
+		}
+	}
+}
+
+// writer returns a writer that prints any pending events before writing to w.
+func (p *runPrinter) writer(w io.Writer) io.Writer {
+	return runPrinterWriter{p: p, w: w}
+}
+
+type runPrinterWriter struct {
+	p *runPrinter
+	w io.Writer
+}
+
+func (pw runPrinterWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+
+	pw.p.drainLocked()
+	return pw.w.Write(b)
+}
+
+// printRunEvent prints a line of converge progress for the given event.
+func printRunEvent(e manager.Event) {
+	name := filepath.Base(e.Module)
+	kind := "module"
+	if e.Directive != "" {
+		name = filepath.Base(e.Directive)
+		kind = "directive"
+	}
+
+	switch e.Type {
+	case manager.EventDirectiveStarted, manager.EventModuleStarted:
+		fmt.Printf("[RUN]  %s %s\n", kind, name)
+	case manager.EventDirectiveFinished, manager.EventModuleFinished:
+		if e.Error != "" {
+			fmt.Printf("[FAIL] %s %s: %s\n", kind, name, e.Error)
+			return
+		}
+		fmt.Printf("[OK]   %s %s\n", kind, name)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+var (
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show how a host is enrolled in the inventory",
+		Long: "Command to show whether the provided host is enrolled in the inventory, the groups it's a member of," +
+			" and the roles and modules it gets. With `--verbose`, the sources each role and module is assigned" +
+			" from (the host's own entry, the `_default` host entry, a group, or a role) are shown as well",
+		Args: cobra.ExactArgs(0),
+		Run:  status,
+	}
+)
+
+func init() {
+	statusCmdFlagSet := statusCmd.Flags()
+	// not bound to viper, to avoid clobbering the `inventory` command's
+	// binding of the same flags
+	statusCmdFlagSet.StringP("inventory.path", "i", "", "Path to mango configuration inventory")
+	statusCmdFlagSet.String("hostname", "", "Hostname to show the status of [default is system hostname]")
+	statusCmdFlagSet.BoolP("verbose", "v", false, "Show the sources each role and module is assigned from")
+	rootCmd.AddCommand(statusCmd)
+}
+
+func status(cmd *cobra.Command, args []string) {
+	logger := slog.Default().With("component", "status")
+	inventoryPath, _ := cmd.Flags().GetString("inventory.path")
+	if inventoryPath == "" {
+		// fall back to the config file, if set there
+		inventoryPath = viper.GetString("inventory.path")
+	}
+	if inventoryPath == "" {
+		logger.Error("Inventory not defined, please set `--inventory.path` flag")
+		os.Exit(1)
+	}
+	hostname, _ := cmd.Flags().GetString("hostname")
+	if hostname == "" {
+		hostname = utils.GetHostname()
+	}
+
+	inv := inventory.NewInventory(inventoryPath, hostname)
+	// the status is the output, so don't log inventory parsing noise
+	inv.Reload(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	printStatus(inv.GetEnrollmentInfoForSelf(), verbose)
+}
+
+func printStatus(info inventory.EnrollmentInfo, verbose bool) {
+	fmt.Printf("Host: %s\n", info.Host)
+	fmt.Printf("Enrolled: %t\n", info.Enrolled)
+	fmt.Printf("Direct host entry: %t\n", info.Direct)
+	fmt.Printf("Groups: %s\n", joinOrNone(info.Groups))
+
+	for _, section := range []struct {
+		name    string
+		sources map[string][]string
+	}{
+		{"Roles", info.Roles},
+		{"Modules", info.Modules},
+	} {
+		names := slices.Sorted(maps.Keys(section.sources))
+		if !verbose {
+			fmt.Printf("%s: %s\n", section.name, joinOrNone(names))
+			continue
+		}
+
+		fmt.Printf("%s:\n", section.name)
+		if len(names) == 0 {
+			fmt.Println("  none")
+		}
+		for _, name := range names {
+			fmt.Printf("  %s (from %s)\n", name, strings.Join(section.sources[name], ", "))
+		}
+	}
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+
+	return strings.Join(items, ", ")
+}
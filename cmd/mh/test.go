@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/inventory"
+	"github.com/tjhop/mango/internal/manager"
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+var (
+	testCmd = &cobra.Command{
+		Use:   "test",
+		Short: "Run the test scripts of a host's modules",
+		Long: "Command to run only the `test` scripts of the modules for the provided host, without applying anything." +
+			" Prints a pass/fail result per module and exits non-zero if any test fails, for use as a CI gate" +
+			" against a mock inventory",
+		Args: cobra.ExactArgs(0),
+		Run:  test,
+	}
+)
+
+func init() {
+	testCmdFlagSet := testCmd.Flags()
+	// not bound to viper, to avoid clobbering the `inventory` command's
+	// binding of the same flags
+	testCmdFlagSet.StringP("inventory.path", "i", "", "Path to mango configuration inventory to test")
+	testCmdFlagSet.String("hostname", "", "Hostname to run the module tests for [default is system hostname]")
+	rootCmd.AddCommand(testCmd)
+}
+
+func test(cmd *cobra.Command, args []string) {
+	logger := slog.Default().With("component", "test")
+	inventoryPath, _ := cmd.Flags().GetString("inventory.path")
+	if inventoryPath == "" {
+		// fall back to the config file, if set there
+		inventoryPath = viper.GetString("inventory.path")
+	}
+	if inventoryPath == "" {
+		logger.Error("Inventory not defined, please set `--inventory.path` flag")
+		os.Exit(1)
+	}
+	hostname, _ := cmd.Flags().GetString("hostname")
+	if hostname == "" {
+		hostname = utils.GetHostname()
+	}
+
+	// scripts are run the same way mango runs them, so they need somewhere
+	// to log and run that doesn't require root
+	dir, err := os.MkdirTemp("", "mh-test-")
+	if err != nil {
+		logger.Error("Error creating temporary directory for test runs", "err", err)
+		os.Exit(1)
+	}
+	viper.Set("mango.log-dir", dir)
+	viper.Set("mango.temp-dir", dir)
+	// keep in sync with mango's default
+	viper.SetDefault("manager.stderr-tail-lines", 10)
+
+	ctx := context.Background()
+	inv := inventory.NewInventory(inventoryPath, hostname)
+	inv.Reload(ctx, logger)
+	if !inv.IsEnrolled() {
+		logger.Warn("Host is not enrolled in the inventory, no modules to test", "hostname", hostname)
+	}
+
+	mgr := manager.NewManager(hostname)
+	results, err := mgr.TestModules(ctx, logger, inv)
+	if err != nil {
+		logger.Error("Error running module tests", "err", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, res := range results {
+		name := filepath.Base(res.Module)
+		switch {
+		case res.Skipped != "":
+			fmt.Printf("[SKIP] %s: %s\n", name, res.Skipped)
+		case res.Err != nil:
+			failed++
+			fmt.Printf("[FAIL] %s: %s\n", name, res.Err)
+		case res.ExitCode != 0:
+			failed++
+			fmt.Printf("[FAIL] %s: exit code %d\n", name, res.ExitCode)
+			if res.StderrTail != "" {
+				fmt.Printf("%s\n", res.StderrTail)
+			}
+		default:
+			fmt.Printf("[PASS] %s\n", name)
+		}
+	}
+
+	fmt.Printf("\nScript logs written to %s\n", filepath.Join(dir, "manager/run"))
+	if failed > 0 {
+		fmt.Printf("%d of %d module tests failed\n", failed, len(results))
+		os.Exit(1)
+	}
+}
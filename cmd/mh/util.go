@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/tjhop/mango/internal/inventory"
 )
 
 var (
@@ -36,6 +38,34 @@ func inventoryAddDir(name string) error {
 	return nil
 }
 
+func inventoryWriteLines(name string, lines []string) error {
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		return fmt.Errorf("Error writing file <%s>: %s", name, err)
+	}
+
+	return nil
+}
+
+// inventorySetDisabled creates or removes the `disabled` marker file in the
+// directory of an existing inventory component (host/module/role/group).
+func inventorySetDisabled(dir string, disabled bool) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("Error finding inventory directory <%s>: %s", dir, err)
+	}
+
+	marker := filepath.Join(dir, inventory.DisabledMarker)
+	if disabled {
+		return inventoryAddFile(marker)
+	}
+
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error removing file <%s>: %s", marker, err)
+	}
+
+	return nil
+}
+
 func inventoryRemoveAll(name string) error {
 	err := os.RemoveAll(name)
 	if err != nil {
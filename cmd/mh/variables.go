@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/manager"
+)
+
+var (
+	invVarsCmd = &cobra.Command{
+		Use:     "vars",
+		Aliases: []string{"variables"},
+		Short:   "Show the effective variables for a host",
+		Long: "Command to show the effective variables for a host (and optionally a module)," +
+			" along with the variables file that set each variable's value",
+		Args: cobra.ExactArgs(0),
+		Run:  inventoryVars,
+	}
+)
+
+func init() {
+	invVarsCmdFlagSet := invVarsCmd.Flags()
+	invVarsCmdFlagSet.String("module", "", "Include the variables for the named module, as they would be provided when running the module")
+	if err := viper.BindPFlags(invVarsCmdFlagSet); err != nil {
+		panic(fmt.Errorf("Error binding flags for command <%s>: %s", "inventory vars", err))
+	}
+	inventoryCmd.AddCommand(invVarsCmd)
+}
+
+func inventoryVars(cmd *cobra.Command, args []string) {
+	logger := slog.Default().With("component", "variables")
+	inv := loadInventory()
+	mgr := manager.NewManager(inv.GetHostname())
+
+	vars, err := mgr.VariableSources(context.Background(), logger, inv, viper.GetString("module"))
+	if err != nil {
+		logger.Error("Error getting variables", "err", err)
+		os.Exit(1)
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE\tSOURCE")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, vars[name].Value, vars[name].Source)
+	}
+	w.Flush()
+}
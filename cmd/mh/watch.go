@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Command to watch the converge status of the given mango server",
+		Long:  "Command to watch the converge status of the given mango server. Polls the mango server's metrics and renders a refreshing table of module/directive run status until interrupted.",
+		Args:  cobra.ExactArgs(0),
+		Run:   mangoWatch,
+	}
+)
+
+func init() {
+	watchCmdFlagSet := watchCmd.Flags()
+	// not bound to viper, to avoid clobbering the `mango` command's binding
+	// of the same flag
+	watchCmdFlagSet.String("address", defaultMangoAddr, "Address of the running mango server")
+	watchCmdFlagSet.Duration("interval", 2*time.Second, "How frequently to poll the mango server's metrics")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchRow contains the run status of a single module script or directive, as
+// assembled from the mango server's metrics.
+type watchRow struct {
+	name        string
+	script      string
+	lastRun     float64
+	lastSuccess float64
+	durationSum float64
+	durationCnt uint64
+	runs        float64
+	failures    float64
+}
+
+func (r *watchRow) status(runInProgress bool, latestRun float64) string {
+	switch {
+	case r.lastRun == 0:
+		return "pending"
+	case r.lastSuccess >= r.lastRun:
+		return "ok"
+	case runInProgress && r.lastRun == latestRun:
+		return "running"
+	default:
+		return "failed"
+	}
+}
+
+func mangoWatch(cmd *cobra.Command, args []string) {
+	addr, _ := cmd.Flags().GetString("address")
+	if !cmd.Flags().Changed("address") && viper.IsSet("address") {
+		// fall back to the config file, if set there
+		addr = viper.GetString("address")
+	}
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		body, err := httpGetBody(addr, "metrics", nil)
+		if err != nil {
+			slog.Error("Error getting metrics from mango server", "err", err, "address", addr)
+		} else if err := renderWatch(addr, interval, body); err != nil {
+			slog.Error("Error rendering mango server status", "err", err, "address", addr)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func renderWatch(addr string, interval time.Duration, body string) error {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Error parsing metrics: %s", err)
+	}
+
+	modules := make(map[string]*watchRow)
+	directives := make(map[string]*watchRow)
+	getRow := func(rows map[string]*watchRow, m *dto.Metric, nameLabel string) *watchRow {
+		name, script := labelValue(m, nameLabel), labelValue(m, "script")
+		key := name + "\x00" + script
+		if _, found := rows[key]; !found {
+			rows[key] = &watchRow{name: name, script: script}
+		}
+		return rows[key]
+	}
+
+	for name, mf := range families {
+		for _, m := range mf.GetMetric() {
+			switch name {
+			case "mango_manager_module_run_timestamp_seconds":
+				getRow(modules, m, "module").lastRun = m.GetGauge().GetValue()
+			case "mango_manager_module_run_success_timestamp_seconds":
+				getRow(modules, m, "module").lastSuccess = m.GetGauge().GetValue()
+			case "mango_manager_module_run_duration_seconds":
+				row := getRow(modules, m, "module")
+				row.durationSum = m.GetHistogram().GetSampleSum()
+				row.durationCnt = m.GetHistogram().GetSampleCount()
+			case "mango_manager_module_run_total":
+				getRow(modules, m, "module").runs = m.GetCounter().GetValue()
+			case "mango_manager_module_run_failed_total":
+				getRow(modules, m, "module").failures = m.GetCounter().GetValue()
+			case "mango_manager_directive_run_timestamp_seconds":
+				getRow(directives, m, "directive").lastRun = m.GetGauge().GetValue()
+			case "mango_manager_directive_run_success_timestamp_seconds":
+				getRow(directives, m, "directive").lastSuccess = m.GetGauge().GetValue()
+			case "mango_manager_directive_run_total":
+				getRow(directives, m, "directive").runs = m.GetCounter().GetValue()
+			case "mango_manager_directive_run_failed_total":
+				getRow(directives, m, "directive").failures = m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	var hostname, enrolled, inventoryPath string
+	for _, m := range families["mango_inventory_info"].GetMetric() {
+		hostname, enrolled, inventoryPath = labelValue(m, "hostname"), labelValue(m, "enrolled"), labelValue(m, "inventory_path")
+	}
+
+	runInProgress := false
+	for _, m := range families["mango_manager_run_in_progress"].GetMetric() {
+		if m.GetGauge().GetValue() == 1 {
+			runInProgress = true
+		}
+	}
+
+	var lastReload float64
+	for _, m := range families["mango_inventory_reload_timestamp_seconds"].GetMetric() {
+		lastReload = max(lastReload, m.GetGauge().GetValue())
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "mango @ %s -- refreshed %s (every %s, ctrl-c to exit)\n\n", addr, now.Format(time.TimeOnly), interval)
+	fmt.Fprintf(&sb, "Hostname: %s\tEnrolled: %s\tInventory: %s\n", hostname, enrolled, inventoryPath)
+	fmt.Fprintf(&sb, "Run in progress: %t\tLast inventory reload: %s\n\n", runInProgress, sinceTimestamp(now, lastReload))
+
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tSCRIPT\tSTATUS\tLAST RUN\tLAST SUCCESS\tAVG DURATION\tRUNS\tFAILURES")
+	latestRun := 0.0
+	for _, row := range modules {
+		latestRun = max(latestRun, row.lastRun)
+	}
+	for _, row := range sortedWatchRows(modules) {
+		avg := "-"
+		if row.durationCnt > 0 {
+			avg = (time.Duration(row.durationSum / float64(row.durationCnt) * float64(time.Second))).Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%.0f\t%.0f\n",
+			row.name, row.script, row.status(runInProgress, latestRun),
+			sinceTimestamp(now, row.lastRun), sinceTimestamp(now, row.lastSuccess),
+			avg, row.runs, row.failures,
+		)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "DIRECTIVE\tSTATUS\tLAST RUN\tLAST SUCCESS\tRUNS\tFAILURES")
+	for _, row := range sortedWatchRows(directives) {
+		status := "ok"
+		if row.failures > 0 && row.lastSuccess < row.lastRun {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.0f\t%.0f\n",
+			row.name, status,
+			sinceTimestamp(now, row.lastRun), sinceTimestamp(now, row.lastSuccess),
+			row.runs, row.failures,
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("Error writing status table: %s", err)
+	}
+
+	// clear the screen and move the cursor home before redrawing
+	fmt.Print("\033[H\033[2J" + sb.String())
+
+	return nil
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+
+	return ""
+}
+
+func sortedWatchRows(rows map[string]*watchRow) []*watchRow {
+	sorted := make([]*watchRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row)
+	}
+	slices.SortFunc(sorted, func(a, b *watchRow) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		// list test scripts before apply scripts, matching the order mango runs them
+		return strings.Compare(b.script, a.script)
+	})
+
+	return sorted
+}
+
+func sinceTimestamp(now time.Time, ts float64) string {
+	if ts == 0 {
+		return "never"
+	}
+
+	return now.Sub(time.Unix(int64(ts), 0)).Round(time.Second).String() + " ago"
+}
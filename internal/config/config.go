@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Name is the name (without extension) of the config file that mango
+	// and mh look for in the config search paths
+	Name = "mango"
+	// EnvPrefix is the prefix of environment variables that can be used to
+	// set any option
+	EnvPrefix = "MANGO"
+)
+
+// SearchPaths are the directories searched (in order) for the config file,
+// if a config file isn't explicitly provided
+var SearchPaths = []string{"/etc/mango", "$HOME/mango", "."}
+
+// ReadInConfig reads the config file into viper, so that config file values
+// are used for any flags that aren't explicitly set. If path is empty, the
+// config search paths are searched for a config file named `mango` (ie,
+// `mango.yaml`), and it is not an error if none is found. It returns the path
+// of the config file that was read, if any.
+func ReadInConfig(path string) (string, error) {
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName(Name)
+		for _, p := range SearchPaths {
+			viper.AddConfigPath(p)
+		}
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if path == "" && errors.As(err, &notFound) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("Failed to read config file: %v", err)
+	}
+
+	return viper.ConfigFileUsed(), nil
+}
+
+// AutomaticEnv enables reading options from environment variables, so that
+// any option can be set with an environment variable named after the option
+// with a `MANGO_` prefix, with dots and dashes replaced by underscores (ie,
+// `--inventory.reload-interval` can be set with
+// `MANGO_INVENTORY_RELOAD_INTERVAL`). Environment variables take precedence
+// over the config file, but not over flags.
+func AutomaticEnv() {
+	automaticEnv(viper.GetViper())
+}
+
+func automaticEnv(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+}
+
+// ReadConfigFile reads the config file into a new viper instance, bound to the
+// flags and environment variables with the same precedence as the global
+// instance. This allows the config file to be reread at runtime (ie, when it
+// changes) without writing to the global instance, which isn't safe to do
+// concurrently with the reads made by runs.
+func ReadConfigFile(path string, flags *pflag.FlagSet) (*viper.Viper, error) {
+	v := viper.New()
+	if err := v.BindPFlags(flags); err != nil {
+		return nil, fmt.Errorf("Failed to bind flags: %v", err)
+	}
+	automaticEnv(v)
+
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("Failed to read config file: %v", err)
+	}
+
+	return v, nil
+}
+
+// UnknownKeys returns the keys set in the config file that aren't in the
+// provided set of known keys (ie, the names of the registered flags), sorted.
+// Unknown keys are otherwise silently ignored, so this is used to catch typos
+// in the config file.
+func UnknownKeys(configFile string, known []string) ([]string, error) {
+	// read the config file into a separate instance, so that only keys from
+	// the config file are checked (and not flags/env/defaults)
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("Failed to read config file: %v", err)
+	}
+
+	knownKeys := make(map[string]struct{}, len(known))
+	for _, k := range known {
+		knownKeys[strings.ToLower(k)] = struct{}{}
+	}
+
+	var unknown []string
+	for _, k := range v.AllKeys() {
+		if _, found := knownKeys[k]; !found {
+			unknown = append(unknown, k)
+		}
+	}
+	slices.Sort(unknown)
+
+	return unknown, nil
+}
+
+// SuggestKey returns the known key that the unknown key was most likely meant
+// to be (ie, `inventory.reload_interval` for `inventory.reload-interval`), or
+// an empty string if there's no likely match.
+func SuggestKey(unknown string, known []string) string {
+	normalized := strings.ReplaceAll(unknown, "_", "-")
+	for _, k := range known {
+		if strings.EqualFold(k, normalized) {
+			return k
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+)
+
+// GetDuration parses the value of the config key as a duration (ie, `30s`). An
+// empty value is returned as 0. Negative durations are rejected. Errors include
+// the offending key.
+func GetDuration(key string) (time.Duration, error) {
+	return ParseDuration(key, viper.GetString(key))
+}
+
+// ParseDuration is like GetDuration, but parses the provided value of the
+// config key (ie, from a separate viper instance).
+func ParseDuration(key, val string) (time.Duration, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 0, nil
+	}
+
+	dur, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse duration for %s: %v", key, err)
+	}
+
+	if dur < 0 {
+		return 0, fmt.Errorf("Failed to parse duration for %s: duration must not be negative: %s", key, val)
+	}
+
+	return dur, nil
+}
+
+// GetSize parses the value of the config key as a size in bytes (ie, `512MiB`
+// or `1GB`). An empty value is returned as 0. Errors include the offending key.
+func GetSize(key string) (uint64, error) {
+	val := strings.TrimSpace(viper.GetString(key))
+	if val == "" {
+		return 0, nil
+	}
+
+	size, err := humanize.ParseBytes(val)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse size for %s: %v", key, err)
+	}
+
+	return size, nil
+}
+
+// GetSchedule parses the value of the config key as a standard 5 field cron
+// expression (ie, `*/30 9-17 * * 1-5`), or a descriptor (ie, `@hourly`). An
+// empty value is returned as a nil schedule. Errors include the offending key.
+func GetSchedule(key string) (cron.Schedule, error) {
+	return ParseSchedule(key, viper.GetString(key))
+}
+
+// ParseSchedule is like GetSchedule, but parses the provided value of the
+// config key (ie, from a separate viper instance).
+func ParseSchedule(key, val string) (cron.Schedule, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return nil, nil
+	}
+
+	schedule, err := cron.ParseStandard(val)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse schedule for %s: %v", key, err)
+	}
+
+	return schedule, nil
+}
+
+// GetFileMode parses the value of the config key as an octal file permission
+// mode (ie, `0640`). An empty value is returned as 0. Modes with bits outside
+// of the permission bits are rejected. Errors include the offending key.
+func GetFileMode(key string) (os.FileMode, error) {
+	val := strings.TrimSpace(viper.GetString(key))
+	if val == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(val, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse file mode for %s: %v", key, err)
+	}
+
+	if os.FileMode(mode)&^os.ModePerm != 0 {
+		return 0, fmt.Errorf("Failed to parse file mode for %s: mode must only contain permission bits: %s", key, val)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// Validate parses each of the provided duration, size, schedule, and file mode
+// config keys, so that invalid values are reported at startup rather than when
+// they are first used. All invalid values are returned as a joined error.
+func Validate(durationKeys, sizeKeys, scheduleKeys, modeKeys []string) error {
+	var errs []error
+
+	for _, key := range durationKeys {
+		if _, err := GetDuration(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, key := range sizeKeys {
+		if _, err := GetSize(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, key := range scheduleKeys {
+		if _, err := GetSchedule(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, key := range modeKeys {
+		if _, err := GetFileMode(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+// loadHostnameAliases returns the system's configured hostname aliases, from
+// `inventory.hostname-aliases` and the newline delimited
+// `inventory.hostname-aliases-file`. Blank lines and lines starting with `#`
+// in the file are ignored.
+func loadHostnameAliases(ctx context.Context, logger *slog.Logger) []string {
+	var aliases []string
+	for _, alias := range viper.GetStringSlice("inventory.hostname-aliases") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	aliasesFile := viper.GetString("inventory.hostname-aliases-file")
+	if aliasesFile == "" {
+		return slices.Compact(aliases)
+	}
+
+	for line := range utils.ReadFileLines(aliasesFile) {
+		if line.Err != nil {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"Failed to read hostname aliases file",
+				slog.String("err", line.Err.Error()),
+				slog.String("path", aliasesFile),
+			)
+			continue
+		}
+
+		alias := strings.TrimSpace(line.Text)
+		if alias == "" || strings.HasPrefix(alias, "#") {
+			continue
+		}
+
+		aliases = append(aliases, alias)
+	}
+
+	return slices.Compact(aliases)
+}
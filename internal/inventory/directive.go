@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/tjhop/mango/pkg/utils"
@@ -17,8 +18,30 @@ import (
 // These scripts are executed first when changes are detected in the inventory, if and only if the
 // script has a modification time within the last 24h.
 // - ID: string idenitfying the directive script (generally the file path to the script)
+// - Phase: when the directive is run relative to modules, one of `pre` (default) or `post`
 type Directive struct {
-	ID string
+	ID    string
+	Phase string
+}
+
+const (
+	// DirectivePhasePre is the phase for directives that are run before
+	// any modules. Directives are in this phase unless marked otherwise.
+	DirectivePhasePre = "pre"
+	// DirectivePhasePost is the phase for directives that are run after
+	// all modules.
+	DirectivePhasePost = "post"
+)
+
+// directivePhase returns the phase of a directive based on its file name.
+// Directive scripts named with a `post-` prefix are run after modules,
+// everything else is run before modules.
+func directivePhase(name string) string {
+	if strings.HasPrefix(name, DirectivePhasePost+"-") {
+		return DirectivePhasePost
+	}
+
+	return DirectivePhasePre
 }
 
 // String is a stringer to return the module ID
@@ -38,7 +61,7 @@ func (i *Inventory) ParseDirectives(ctx context.Context, logger *slog.Logger) er
 	)
 
 	path := filepath.Join(i.inventoryPath, "directives")
-	files, err := utils.GetFilesInDirectory(path)
+	files, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(path))
 	if err != nil {
 		iLogger.LogAttrs(
 			ctx,
@@ -61,7 +84,8 @@ func (i *Inventory) ParseDirectives(ctx context.Context, logger *slog.Logger) er
 			scriptPath := filepath.Join(path, file.Name())
 
 			dirScripts = append(dirScripts, Directive{
-				ID: scriptPath,
+				ID:    scriptPath,
+				Phase: directivePhase(file.Name()),
 			})
 		}
 	}
@@ -0,0 +1,96 @@
+package inventory
+
+import (
+	"maps"
+	"slices"
+)
+
+// EnrollmentInfo details all of the ways a host is enrolled in the inventory,
+// and the roles and modules it gets as a result.
+// - Host: the hostname the enrollment info is for
+// - Enrolled: whether the host is enrolled, either directly or via a group
+// - Direct: whether the host has its own host entry in the inventory
+// - Groups: names of the groups the host is a member of
+// - Roles: names of the roles assigned to the host, mapped to the sources
+// they're assigned from
+// - Modules: IDs of the modules assigned to the host, mapped to the sources
+// they're assigned from
+//
+// Sources are one of `host` (the host's own entry), `_default` (the default
+// host entry), `group/<name>`, or `role/<name>` (for modules included by a
+// role, which itself may be assigned from multiple sources).
+type EnrollmentInfo struct {
+	Host     string              `json:"host"`
+	Enrolled bool                `json:"enrolled"`
+	Direct   bool                `json:"direct"`
+	Groups   []string            `json:"groups"`
+	Roles    map[string][]string `json:"roles"`
+	Modules  map[string][]string `json:"modules"`
+}
+
+// addSource records that the named item is assigned from the source, skipping
+// items that don't exist in the inventory.
+func addSource(sources map[string][]string, name, source string, exists bool) {
+	if !exists || slices.Contains(sources[name], source) {
+		return
+	}
+
+	sources[name] = append(sources[name], source)
+}
+
+// GetEnrollmentInfoForHost returns the details of how the specified host is
+// enrolled in the inventory, aggregated from the host's own entry, the
+// `_default` host entry, and any groups the host is a member of.
+func (i *Inventory) GetEnrollmentInfoForHost(host string) EnrollmentInfo {
+	info := EnrollmentInfo{
+		Host:    host,
+		Groups:  []string{},
+		Roles:   make(map[string][]string),
+		Modules: make(map[string][]string),
+	}
+
+	_, info.Direct = i.GetHost(host)
+	info.Enrolled = i.IsHostEnrolled(host)
+	if !info.Enrolled {
+		return info
+	}
+
+	addAssignments := func(source string, roles, modules []string) {
+		for _, r := range roles {
+			_, found := i.GetRole(r)
+			addSource(info.Roles, r, source, found)
+		}
+		for _, m := range modules {
+			_, found := i.GetModule(m)
+			addSource(info.Modules, m, source, found)
+		}
+	}
+
+	if i.defaultHost != nil {
+		addAssignments(DefaultHostName, i.defaultHost.roles, i.defaultHost.modules)
+	}
+
+	if h, found := i.GetHost(host); found {
+		addAssignments("host", h.roles, h.modules)
+	}
+
+	for _, g := range i.GetGroupsForHost(host) {
+		info.Groups = append(info.Groups, g.String())
+		addAssignments("group/"+g.String(), g.roles, g.modules)
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(info.Roles)) {
+		for _, m := range i.GetModulesForRole(role) {
+			addSource(info.Modules, m.ID, "role/"+role, true)
+		}
+	}
+
+	slices.Sort(info.Groups)
+	return info
+}
+
+// GetEnrollmentInfoForSelf returns the details of how the running system is
+// enrolled in the inventory.
+func (i *Inventory) GetEnrollmentInfoForSelf() EnrollmentInfo {
+	return i.GetEnrollmentInfoForHost(i.selfHostname())
+}
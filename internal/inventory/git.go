@@ -0,0 +1,108 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shortSHALength is the length of the abbreviated commit SHA reported for git
+// based inventories.
+const shortSHALength = 7
+
+// gitCommit returns the abbreviated SHA of the commit currently checked out
+// in the git repository at the given path. It reads the repository files
+// directly rather than depending on `git` being installed.
+func gitCommit(path string) (string, error) {
+	gitDir, err := resolveGitDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("Failed to read git HEAD: %v", err)
+	}
+
+	sha := strings.TrimSpace(string(head))
+	if ref, found := strings.CutPrefix(sha, "ref: "); found {
+		sha, err = resolveGitRef(gitDir, ref)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(sha) < shortSHALength {
+		return "", fmt.Errorf("Invalid git commit SHA: %q", sha)
+	}
+
+	return sha[:shortSHALength], nil
+}
+
+// resolveGitDir returns the git directory for the repository at path,
+// following `gitdir:` files used by worktrees and submodules.
+func resolveGitDir(path string) (string, error) {
+	gitDir := filepath.Join(path, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", fmt.Errorf("Failed to find git directory: %v", err)
+	}
+
+	if info.IsDir() {
+		return gitDir, nil
+	}
+
+	data, err := os.ReadFile(gitDir)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read git directory file: %v", err)
+	}
+
+	dir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !found {
+		return "", fmt.Errorf("Invalid git directory file: %s", gitDir)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+
+	return dir, nil
+}
+
+// resolveGitRef returns the SHA the named ref points to, checking loose refs
+// before packed refs. Worktrees share refs with the main repository via the
+// `commondir` file, so that is checked as well.
+func resolveGitRef(gitDir, ref string) (string, error) {
+	dirs := []string{gitDir}
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		dir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(gitDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	for _, dir := range dirs {
+		if data, err := os.ReadFile(filepath.Join(dir, ref)); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+
+		f, err := os.Open(filepath.Join(dir, "packed-refs"))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			sha, name, found := strings.Cut(scanner.Text(), " ")
+			if found && name == ref {
+				f.Close()
+				return sha, nil
+			}
+		}
+		f.Close()
+	}
+
+	return "", fmt.Errorf("Failed to resolve git ref: %s", ref)
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/tjhop/mango/pkg/utils"
@@ -25,6 +26,7 @@ var (
 // - roles: a slice of roles that are applied to this host
 // - modules: a slice of ad-hoc module names applied to this host
 // - variables: path to the variables file for this group, if present
+// - variablesDir: paths of the files in the group's `variables.d` directory, if present
 // - templateFiles: slice of paths of user defined template files
 type Group struct {
 	id            string
@@ -33,6 +35,7 @@ type Group struct {
 	modules       []string
 	roles         []string
 	variables     string
+	variablesDir  []string
 	templateFiles []string
 }
 
@@ -57,7 +60,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 	)
 
 	path := filepath.Join(i.inventoryPath, "groups")
-	groupDirs, err := utils.GetFilesInDirectory(path)
+	groupDirs, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(path))
 	if err != nil {
 		iLogger.LogAttrs(
 			ctx,
@@ -78,7 +81,17 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 	for _, groupDir := range groupDirs {
 		if groupDir.IsDir() && !utils.IsHidden(groupDir.Name()) {
 			groupPath := filepath.Join(path, groupDir.Name())
-			groupFiles, err := utils.GetFilesInDirectory(groupPath)
+			if i.isDisabled(groupPath) {
+				iLogger.LogAttrs(
+					ctx,
+					slog.LevelInfo,
+					"Skipping disabled group while parsing inventory",
+					slog.String("path", groupPath),
+				)
+				continue
+			}
+
+			groupFiles, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(groupPath))
 			if err != nil {
 				iLogger.LogAttrs(
 					ctx,
@@ -97,6 +110,10 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 			group := Group{id: groupDir.Name()}
 
 			for _, groupFile := range groupFiles {
+				if groupFile.IsDir() && groupFile.Name() == VariablesDirName {
+					group.variablesDir = i.parseVariablesDir(ctx, iLogger, filepath.Join(groupPath, VariablesDirName))
+				}
+
 				if groupFile.IsDir() && groupFile.Name() == "templates" {
 					templatedir := filepath.Join(groupPath, "templates")
 
@@ -107,7 +124,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 					// > ErrBadPattern, when pattern is
 					// > malformed.
 					// ...I'm making the pattern. I know it's not malformed.
-					matchedTpls, _ := filepath.Glob(filepath.Join(templatedir, "*.tpl"))
+					matchedTpls, _ := i.glob(templatedir, "*.tpl")
 					group.templateFiles = matchedTpls
 				}
 
@@ -117,7 +134,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 					case "glob":
 						var globs []string
 						globPath := filepath.Join(groupPath, "glob")
-						lines := utils.ReadFileLines(globPath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(globPath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -137,7 +154,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 					case "regex":
 						var patterns []string
 						patternPath := filepath.Join(groupPath, "regex")
-						lines := utils.ReadFileLines(patternPath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(patternPath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -157,7 +174,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 					case "roles":
 						var roles []string
 						rolePath := filepath.Join(groupPath, "roles")
-						lines := utils.ReadFileLines(rolePath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(rolePath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -177,7 +194,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 					case "modules":
 						var mods []string
 						modPath := filepath.Join(groupPath, "modules")
-						lines := utils.ReadFileLines(modPath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(modPath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -215,7 +232,7 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 	metricInventory.With(commonLabels).Set(float64(len(i.groups)))
 	groupMatches := 0
 	for _, group := range i.groups {
-		if group.IsHostEnrolled(i.hostname) {
+		if group.IsHostEnrolled(i.selfHostname()) {
 			groupMatches++
 		}
 	}
@@ -229,8 +246,19 @@ func (i *Inventory) ParseGroups(ctx context.Context, logger *slog.Logger) error
 func (g Group) MatchGlobs(hostname string) int {
 	matched := 0
 
+	// only lower case for the comparison, the stored globs are left alone
+	caseInsensitive := caseInsensitiveHostnames()
+	if caseInsensitive {
+		hostname = strings.ToLower(hostname)
+	}
+
 	for _, globPattern := range g.globs {
-		glob, err := glob_util.Compile(globPattern)
+		compilePattern := globPattern
+		if caseInsensitive {
+			compilePattern = strings.ToLower(globPattern)
+		}
+
+		glob, err := glob_util.Compile(compilePattern)
 		if err != nil {
 			slog.LogAttrs(
 				context.Background(),
@@ -254,8 +282,14 @@ func (g Group) MatchGlobs(hostname string) int {
 func (g Group) MatchPatterns(hostname string) int {
 	matched := 0
 
+	caseInsensitive := caseInsensitiveHostnames()
 	for _, pattern := range g.patterns {
-		validPattern, err := regexp.Compile(pattern)
+		compilePattern := pattern
+		if caseInsensitive {
+			compilePattern = "(?i)" + pattern
+		}
+
+		validPattern, err := regexp.Compile(compilePattern)
 		if err != nil {
 			slog.LogAttrs(
 				context.Background(),
@@ -16,27 +16,45 @@ var (
 	ValidHostDirs  = []string{"templates"}
 )
 
+// DefaultHostName is the reserved name of the host entry whose roles,
+// modules, variables, and templates are applied to every enrolled host, with
+// lower precedence than anything else applied to the host. It doesn't enroll
+// a host itself, and its `apply`/`test` scripts are ignored.
+const DefaultHostName = "_default"
+
 // Host contains fields that represent a given host in the inventory.
 // - id: string idenitfying the host (generally the hostname of the system)
 // - roles: a slice of roles that are applied to this host
 // - modules: a slice of ad-hoc module names applied to this host
 // - variables: path to the variables file for this host, if present
+// - variablesDir: paths of the files in the host's `variables.d` directory, if present
 // - templateFiles: slice of paths of user defined template files
+// - Apply: path to the host's own apply script, if present
+// - Test: path to the host's own test script, if present
 type Host struct {
 	id            string
 	modules       []string
 	roles         []string
 	variables     string
+	variablesDir  []string
 	templateFiles []string
+	Apply         string
+	Test          string
 }
 
 // String is a stringer to return the host ID
 func (h Host) String() string { return h.id }
 
+// Variables returns the paths of the host's own variables file and the files
+// in its `variables.d` directory, in the order they are sourced
+func (h Host) Variables() []string { return variableFiles(h.variables, h.variablesDir) }
+
 // ParseHosts looks for hosts in the inventory's `hosts/` folder. It looks for
 // folders within this directory, and then parses each directory into a Host struct.
-// Each host folder is expected to contain files for `apply`, `variables`, and `test`,
-// which get set to the corresponding fields in the Host struct for the host.
+// Each host folder may contain files for `roles`, `modules`, and `variables`,
+// as well as optional `apply` and `test` scripts that are run for the host
+// after all of its modules, which get set to the corresponding fields in the
+// Host struct for the host.
 func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 	commonLabels := prometheus.Labels{
 		"inventory": i.inventoryPath,
@@ -50,7 +68,7 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 	)
 
 	path := filepath.Join(i.inventoryPath, "hosts")
-	hostDirs, err := utils.GetFilesInDirectory(path)
+	hostDirs, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(path))
 	if err != nil {
 		iLogger.LogAttrs(
 			ctx,
@@ -66,12 +84,25 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 		return err
 	}
 
-	var hosts []Host
+	var (
+		hosts       []Host
+		defaultHost *Host
+	)
 
 	for _, hostDir := range hostDirs {
 		if hostDir.IsDir() && !utils.IsHidden(hostDir.Name()) {
 			hostPath := filepath.Join(path, hostDir.Name())
-			hostFiles, err := utils.GetFilesInDirectory(hostPath)
+			if i.isDisabled(hostPath) {
+				iLogger.LogAttrs(
+					ctx,
+					slog.LevelInfo,
+					"Skipping disabled host while parsing inventory",
+					slog.String("path", hostPath),
+				)
+				continue
+			}
+
+			hostFiles, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(hostPath))
 			if err != nil {
 				iLogger.LogAttrs(
 					ctx,
@@ -90,6 +121,10 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 			host := Host{id: hostDir.Name()}
 
 			for _, hostFile := range hostFiles {
+				if hostFile.IsDir() && hostFile.Name() == VariablesDirName {
+					host.variablesDir = i.parseVariablesDir(ctx, iLogger, filepath.Join(hostPath, VariablesDirName))
+				}
+
 				if hostFile.IsDir() && hostFile.Name() == "templates" {
 					templatedir := filepath.Join(hostPath, "templates")
 
@@ -100,7 +135,7 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 					// > ErrBadPattern, when pattern is
 					// > malformed.
 					// ...I'm making the pattern. I know it's not malformed.
-					matchedTpls, _ := filepath.Glob(filepath.Join(templatedir, "*.tpl"))
+					matchedTpls, _ := i.glob(templatedir, "*.tpl")
 					host.templateFiles = matchedTpls
 				}
 
@@ -110,7 +145,7 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 					case "roles":
 						var roles []string
 						rolePath := filepath.Join(hostPath, "roles")
-						lines := utils.ReadFileLines(rolePath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(rolePath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -130,7 +165,7 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 					case "modules":
 						var mods []string
 						modPath := filepath.Join(hostPath, "modules")
-						lines := utils.ReadFileLines(modPath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(modPath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -149,6 +184,10 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 						host.modules = mods
 					case "variables":
 						host.variables = filepath.Join(hostPath, "variables")
+					case "apply":
+						host.Apply = filepath.Join(hostPath, "apply")
+					case "test":
+						host.Test = filepath.Join(hostPath, "test")
 					default:
 						iLogger.LogAttrs(
 							ctx,
@@ -160,11 +199,17 @@ func (i *Inventory) ParseHosts(ctx context.Context, logger *slog.Logger) error {
 				}
 			}
 
+			if host.id == DefaultHostName {
+				defaultHost = &host
+				continue
+			}
+
 			hosts = append(hosts, host)
 		}
 	}
 
 	i.hosts = hosts
+	i.defaultHost = defaultHost
 	metricInventory.With(commonLabels).Set(float64(len(i.hosts)))
 	numMyHosts := 0
 	if i.IsEnrolled() {
@@ -2,9 +2,29 @@ package inventory
 
 import (
 	"context"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// PrecedenceHost is the default variable precedence, where role
+	// variables/templates are provided first, then group, then host (so
+	// host level data wins).
+	PrecedenceHost = "host"
+	// PrecedenceRole reverses the default variable precedence, so that
+	// host variables/templates are provided first, then group, then role
+	// (so centrally managed role level data wins).
+	PrecedenceRole = "role"
 )
 
 // Inventory contains fields that comprise the data that makes up our inventory.
@@ -15,22 +35,79 @@ import (
 // - Groups: a slice of `Group` structs, containing globs/patterns for hostname matching
 type Inventory struct {
 	inventoryPath string
+	fsys          fs.FS // filesystem the inventory is parsed from, rooted at the inventory path
 	hostname      string
+	aliases       []string
 	hosts         []Host
+	defaultHost   *Host // the reserved `_default` host entry, if any
 	modules       []Module
 	roles         []Role
 	directives    []Directive
 	groups        []Group
 }
 
+// DisabledMarker is the name of the marker file that disables the host,
+// module, role, or group whose directory it's in, without having to delete it
+// from the inventory.
+const DisabledMarker = "disabled"
+
+// isDisabled returns true if the inventory component in the provided
+// directory has been disabled with a marker file.
+func (i *Inventory) isDisabled(dir string) bool {
+	_, err := fs.Stat(i.fsys, i.fsPath(filepath.Join(dir, DisabledMarker)))
+	return err == nil
+}
+
+// fsPath returns the path within the inventory's filesystem for the provided
+// path under the inventory path, ie `/inventory/hosts/foo` -> `hosts/foo`.
+func (i *Inventory) fsPath(path string) string {
+	rel, err := filepath.Rel(i.inventoryPath, path)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// glob returns the paths of the files in the provided directory under the
+// inventory path that match the pattern, like `filepath.Glob()`, but using the
+// inventory's filesystem.
+func (i *Inventory) glob(dir, pattern string) ([]string, error) {
+	matches, err := fs.Glob(i.fsys, path.Join(i.fsPath(dir), pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, m := range matches {
+		paths = append(paths, filepath.Join(dir, path.Base(m)))
+	}
+
+	return paths, nil
+}
+
 // String is a stringer to return the inventory path
 func (i *Inventory) String() string { return i.inventoryPath }
 
 // GetInventoryPath returns the inventory path as a string
 func (i *Inventory) GetInventoryPath() string { return i.inventoryPath }
 
-// GetHostname returns the inventory path as a string
-func (i *Inventory) GetHostname() string { return i.hostname }
+// GetHostname returns the name the system is known by in the inventory. This
+// is the system's hostname, unless the hostname isn't enrolled and one of the
+// system's configured aliases is.
+func (i *Inventory) GetHostname() string { return i.selfHostname() }
+
+// selfHostname returns the first of the system's hostname and aliases that is
+// enrolled in the inventory, or the hostname if none are.
+func (i *Inventory) selfHostname() string {
+	for _, name := range append([]string{i.hostname}, i.aliases...) {
+		if i.IsHostEnrolled(name) {
+			return name
+		}
+	}
+
+	return i.hostname
+}
 
 // Store is the set of methods that Inventory must
 // implement to serve as a backing store for an inventory
@@ -69,6 +146,7 @@ type Store interface {
 	GetGroupsForHost(host string) []Group
 	GetVariablesForHost(host string) []string
 	GetTemplatesForHost(host string) []string
+	GetEnrollmentInfoForHost(host string) EnrollmentInfo
 
 	// Self checks
 	GetDirectivesForSelf() []Directive
@@ -77,12 +155,23 @@ type Store interface {
 	GetGroupsForSelf() []Group
 	GetVariablesForSelf() []string
 	GetTemplatesForSelf() []string
+	GetEnrollmentInfoForSelf() EnrollmentInfo
 }
 
 // NewInventory parses the files/directories in the provided path
 // to populate the inventory.
 func NewInventory(path, name string) *Inventory {
+	return NewInventoryWithFS(os.DirFS(path), path, name)
+}
+
+// NewInventoryWithFS returns an inventory that is parsed from the provided
+// filesystem rather than from the provided path directly (ie, an
+// `fstest.MapFS` for testing inventory parsing without touching disk). The
+// path is still used to identify the inventory and its components, so scripts
+// in the inventory can only be run if the filesystem is backed by the path.
+func NewInventoryWithFS(fsys fs.FS, path, name string) *Inventory {
 	i := Inventory{
+		fsys:          fsys,
 		inventoryPath: path,
 		hostname:      name,
 		hosts:         []Host{},
@@ -98,12 +187,30 @@ func NewInventory(path, name string) *Inventory {
 	return &i
 }
 
-// Reload reloads Inventory from it's configured path. Components that are reloaded:
+// Reload reloads Inventory from it's configured path, after first syncing the
+// path from the remote inventory source if one is configured. Components that
+// are reloaded:
 // - Hosts
 // - Roles
 // - Modules
 // - Directives
 func (i *Inventory) Reload(ctx context.Context, logger *slog.Logger) {
+	// reload the system's aliases
+	i.aliases = loadHostnameAliases(ctx, logger)
+
+	success := true
+
+	// sync from remote source, if configured
+	if err := i.syncSource(ctx, logger); err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to sync inventory from remote source, continuing with last synced inventory",
+			slog.String("err", err.Error()),
+		)
+		success = false
+	}
+
 	// populate the inventory
 
 	// parse groups
@@ -114,6 +221,7 @@ func (i *Inventory) Reload(ctx context.Context, logger *slog.Logger) {
 			"Failed to reload groups",
 			slog.String("err", err.Error()),
 		)
+		success = false
 	}
 
 	// parse hosts
@@ -124,6 +232,7 @@ func (i *Inventory) Reload(ctx context.Context, logger *slog.Logger) {
 			"Failed to reload hosts",
 			slog.String("err", err.Error()),
 		)
+		success = false
 	}
 
 	// parse roles
@@ -134,6 +243,7 @@ func (i *Inventory) Reload(ctx context.Context, logger *slog.Logger) {
 			"Failed to reload roles",
 			slog.String("err", err.Error()),
 		)
+		success = false
 	}
 
 	// parse modules
@@ -144,6 +254,7 @@ func (i *Inventory) Reload(ctx context.Context, logger *slog.Logger) {
 			"Failed to reload modules",
 			slog.String("err", err.Error()),
 		)
+		success = false
 	}
 
 	// parse directives
@@ -154,14 +265,41 @@ func (i *Inventory) Reload(ctx context.Context, logger *slog.Logger) {
 			"Failed to reload directives",
 			slog.String("err", err.Error()),
 		)
+		success = false
 	}
 
-	// update inventory metrics -- if enrollment status has changed, unset
-	// old metric value as well as set new value
+	// update aggregate inventory stats, now that all components are parsed
+	i.updateStatsMetrics()
+	i.updateSizeMetrics(ctx, logger)
+
+	// update the overall outcome of the reload
+	labels := prometheus.Labels{"inventory": i.inventoryPath}
+	if success {
+		metricInventoryReloadSuccess.With(labels).Set(1)
+	} else {
+		metricInventoryReloadSuccess.With(labels).Set(0)
+	}
+	metricInventoryLastReloadTimestamp.With(labels).Set(float64(time.Now().Unix()))
+
+	// get the inventory's commit, if it's a git repository
+	commit, err := gitCommit(i.inventoryPath)
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelDebug,
+			"Failed to get git commit for inventory",
+			slog.String("err", err.Error()),
+		)
+		commit = "unknown"
+	}
+
+	// update inventory metrics -- if enrollment status or commit has
+	// changed, unset old metric value as well as set new value
 	enrolled := strconv.FormatBool(i.IsEnrolled())
-	if enrolled != metricMangoInventoryInfoLabels["enrolled"] {
+	if enrolled != metricMangoInventoryInfoLabels["enrolled"] || commit != metricMangoInventoryInfoLabels["commit"] {
 		metricMangoInventoryInfo.Reset()
 		metricMangoInventoryInfoLabels["enrolled"] = enrolled
+		metricMangoInventoryInfoLabels["commit"] = commit
 		metricMangoInventoryInfo.With(metricMangoInventoryInfoLabels).Set(1)
 	}
 }
@@ -177,11 +315,11 @@ func (i *Inventory) IsHostEnrolled(host string) bool {
 	return len(i.GetGroupsForHost(host)) > 0
 }
 
-// IsEnrolled returns if the hostname of the system is defined in the
-// inventory, or if the hostname of the system matches any group match
-// parameters
+// IsEnrolled returns if the hostname (or any of the configured aliases) of the
+// system is defined in the inventory, or if the hostname (or any of the
+// configured aliases) of the system matches any group match parameters
 func (i *Inventory) IsEnrolled() bool {
-	return i.IsHostEnrolled(i.hostname)
+	return i.IsHostEnrolled(i.selfHostname())
 }
 
 // GetDirectives returns a copy of the inventory's slice of Directive
@@ -208,7 +346,7 @@ func (i *Inventory) GetDirectivesForSelf() []Directive {
 // in the inventory.
 func (i *Inventory) GetModule(module string) (Module, bool) {
 	for _, m := range i.modules {
-		if filepath.Base(m.ID) == module {
+		if m.ID == module {
 			return m, true
 		}
 	}
@@ -237,20 +375,31 @@ func (i *Inventory) GetModulesForRole(role string) []Module {
 }
 
 // GetModulesForHost returns a slice of Modules, containing all of the
-// Modules for the specified host system (including modules in all assigned roles, as well as ad-hoc modules).
+// Modules for the specified host system (including modules in all assigned roles, as well as ad-hoc modules,
+// and modules from the `_default` host entry).
 func (i *Inventory) GetModulesForHost(host string) []Module {
 	mods := []Module{}
 
 	if i.IsHostEnrolled(host) {
 		// get modules from all roles host is assigned
+		// roles are looked up by name, while their IDs are their paths
 		for _, r := range i.GetRolesForHost(host) {
-			mods = append(mods, i.GetModulesForRole(r.String())...)
+			mods = append(mods, i.GetModulesForRole(filepath.Base(r.String()))...)
 		}
 
 		for _, g := range i.GetGroupsForHost(host) {
 			mods = append(mods, i.GetModulesForGroup(g.String())...)
 		}
 
+		// get raw modules from the default host entry
+		if i.defaultHost != nil {
+			for _, m := range i.defaultHost.modules {
+				if mod, found := i.GetModule(m); found {
+					mods = append(mods, mod)
+				}
+			}
+		}
+
 		// get raw host modules
 		if h, found := i.GetHost(host); found {
 			for _, m := range h.modules {
@@ -302,7 +451,7 @@ func (i *Inventory) GetRolesForGroup(group string) []Role {
 // GetModulesForSelf returns a slice of Modules, containing all of the
 // Modules for the running system from the inventory.
 func (i *Inventory) GetModulesForSelf() []Module {
-	return i.GetModulesForHost(i.hostname)
+	return i.GetModulesForHost(i.selfHostname())
 }
 
 // GetRole returns a copy of the Role struct for a role identified
@@ -324,10 +473,19 @@ func (i *Inventory) GetRoles() []Role {
 }
 
 // GetRolesForHost returns a slice of Roles, containing all of the
-// Roles applicable to the specified host system.
+// Roles applicable to the specified host system (including roles from the
+// `_default` host entry).
 func (i *Inventory) GetRolesForHost(host string) []Role {
 	if i.IsHostEnrolled(host) {
 		roles := []Role{}
+		if i.defaultHost != nil {
+			for _, r := range i.defaultHost.roles {
+				if role, found := i.GetRole(r); found {
+					roles = append(roles, role)
+				}
+			}
+		}
+
 		if h, found := i.GetHost(host); found {
 			for _, r := range h.roles {
 				if role, found := i.GetRole(r); found {
@@ -353,7 +511,7 @@ func (i *Inventory) GetRolesForHost(host string) []Role {
 // GetRolesForSelf returns a slice of Roles, containing all of the
 // Roles applicable to the running system from the inventory.
 func (i *Inventory) GetRolesForSelf() []Role {
-	return i.GetRolesForHost(i.hostname)
+	return i.GetRolesForHost(i.selfHostname())
 }
 
 // GetHosts returns a copy of the inventory's Hosts.
@@ -366,7 +524,7 @@ func (i *Inventory) GetHosts() []Host {
 // the inventory.
 func (i *Inventory) GetHost(host string) (Host, bool) {
 	for _, h := range i.hosts {
-		if filepath.Base(h.id) == host {
+		if hostnamesEqual(filepath.Base(h.id), host) {
 			return h, true
 		}
 	}
@@ -374,27 +532,70 @@ func (i *Inventory) GetHost(host string) (Host, bool) {
 	return Host{}, false
 }
 
+// caseInsensitiveHostnames returns true if hostnames should be compared case
+// insensitively, as configured by `inventory.case-insensitive-hostnames`.
+func caseInsensitiveHostnames() bool {
+	return viper.GetBool("inventory.case-insensitive-hostnames")
+}
+
+// hostnamesEqual compares the hostnames, case insensitively if configured.
+func hostnamesEqual(a, b string) bool {
+	if caseInsensitiveHostnames() {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+// applyPrecedence returns the provided paths (or per component slices of
+// paths) in the order dictated by the configured
+// `inventory.variable-precedence`. Items are expected to be provided in the
+// default role -> group -> host order.
+func applyPrecedence[T any](items []T) []T {
+	precedence := strings.TrimSpace(strings.ToLower(viper.GetString("inventory.variable-precedence")))
+	if precedence != PrecedenceRole {
+		return items
+	}
+
+	reversed := slices.Clone(items)
+	slices.Reverse(reversed)
+	return reversed
+}
+
 // GetVariablesForHost returns slice of strings, containing the paths of any
 // variables files found for this host. All role variables are provided first,
 // then group variables second, with host-specific variables provided last (to
-// allow for overriding default group variable data).
+// allow for overriding default group variable data). If
+// `inventory.variable-precedence` is set to `role`, the order is reversed.
+// Variables from the `_default` host entry are always provided first. Each
+// component's `variables` file is followed by the files in its `variables.d`
+// directory, regardless of precedence.
 func (i *Inventory) GetVariablesForHost(host string) []string {
-	var varFiles []string
+	var components [][]string
 
 	for _, role := range i.GetRolesForHost(host) {
-		if role.variables != "" {
-			varFiles = append(varFiles, role.variables)
+		if files := variableFiles(role.variables, role.variablesDir); len(files) > 0 {
+			components = append(components, files)
 		}
 	}
 
 	for _, group := range i.GetGroupsForHost(host) {
-		if group.variables != "" {
-			varFiles = append(varFiles, group.variables)
+		if files := variableFiles(group.variables, group.variablesDir); len(files) > 0 {
+			components = append(components, files)
 		}
 	}
 
-	if h, found := i.GetHost(host); found && h.variables != "" {
-		varFiles = append(varFiles, h.variables)
+	if h, found := i.GetHost(host); found {
+		if files := h.Variables(); len(files) > 0 {
+			components = append(components, files)
+		}
+	}
+
+	varFiles := slices.Concat(applyPrecedence(components)...)
+
+	// the default host entry's variables always have the lowest precedence
+	if i.defaultHost != nil && i.IsHostEnrolled(host) {
+		return append(i.defaultHost.Variables(), varFiles...)
 	}
 
 	return varFiles
@@ -405,13 +606,17 @@ func (i *Inventory) GetVariablesForHost(host string) []string {
 // first, then group variables second, with host-specific variables provided
 // last (to allow for overriding default group variable data).
 func (i *Inventory) GetVariablesForSelf() []string {
-	return i.GetVariablesForHost(i.hostname)
+	return i.GetVariablesForHost(i.selfHostname())
 }
 
 // GetTemplatesForHost returns slice of strings, containing the paths of any
 // templates files found for this host. All role templates are provided first,
 // then group templates second, with host-specific templates provided last (to
-// allow for overriding default group variable data).
+// allow for overriding default group variable data). If
+// `inventory.variable-precedence` is set to `role`, the order is reversed.
+// Templates from the `_default` host entry are always provided first. Since
+// templates are associated by file name when parsed, only the highest
+// precedence template is returned for each file name.
 func (i *Inventory) GetTemplatesForHost(host string) []string {
 	var tmpls []string
 
@@ -427,7 +632,12 @@ func (i *Inventory) GetTemplatesForHost(host string) []string {
 		tmpls = append(tmpls, h.templateFiles...)
 	}
 
-	return tmpls
+	// the default host entry's templates always have the lowest precedence
+	if i.defaultHost != nil && i.IsHostEnrolled(host) {
+		return filterDuplicateTemplates(append(slices.Clone(i.defaultHost.templateFiles), applyPrecedence(tmpls)...))
+	}
+
+	return filterDuplicateTemplates(applyPrecedence(tmpls))
 }
 
 // GetTemplatesForSelf returns slice of strings, containing the paths of any
@@ -435,40 +645,57 @@ func (i *Inventory) GetTemplatesForHost(host string) []string {
 // first, then group templates second, with host-specific templates provided
 // last (to allow for overriding default group variable data).
 func (i *Inventory) GetTemplatesForSelf() []string {
-	return i.GetTemplatesForHost(i.hostname)
+	return i.GetTemplatesForHost(i.selfHostname())
 }
 
-func filterDuplicateModules(input []Module) []Module {
-	modMap := make(map[string]Module)
+// filterDuplicateTemplates returns the provided template paths with only the
+// last (highest precedence) path kept for each file name, in their original
+// order.
+func filterDuplicateTemplates(input []string) []string {
+	seen := make(map[string]struct{})
+	var output []string
 
-	for _, m := range input {
-		if _, found := modMap[m.String()]; !found {
-			modMap[m.String()] = m
+	for _, path := range slices.Backward(input) {
+		name := filepath.Base(path)
+		if _, found := seen[name]; !found {
+			seen[name] = struct{}{}
+			output = append(output, path)
 		}
 	}
 
+	slices.Reverse(output)
+	return output
+}
+
+// filterDuplicateModules returns the provided modules with only the first
+// occurrence of each module kept, in their original order.
+func filterDuplicateModules(input []Module) []Module {
+	seen := make(map[string]struct{})
 	var output []Module
-	for _, mod := range modMap {
-		output = append(output, mod)
+
+	for _, m := range input {
+		if _, found := seen[m.String()]; !found {
+			seen[m.String()] = struct{}{}
+			output = append(output, m)
+		}
 	}
 
 	return output
 }
 
+// filterDuplicateRoles returns the provided roles with only the first
+// occurrence of each role kept, in their original order.
 func filterDuplicateRoles(input []Role) []Role {
-	roleMap := make(map[string]Role)
+	seen := make(map[string]struct{})
+	var output []Role
 
 	for _, r := range input {
-		if _, found := roleMap[r.String()]; !found {
-			roleMap[r.String()] = r
+		if _, found := seen[r.String()]; !found {
+			seen[r.String()] = struct{}{}
+			output = append(output, r)
 		}
 	}
 
-	var output []Role
-	for _, role := range roleMap {
-		output = append(output, role)
-	}
-
 	return output
 }
 
@@ -506,15 +733,15 @@ func (i *Inventory) GetGroupsForHost(host string) []Group {
 // GetGroupsForSelf returns a slice of Groups, containing all of the
 // Groups for the running system from the inventory.
 func (i *Inventory) GetGroupsForSelf() []Group {
-	return i.GetGroupsForHost(i.hostname)
+	return i.GetGroupsForHost(i.selfHostname())
 }
 
-// GetVariablesForGroup returns the path of the group's variables file, or the
-// empty string if no group/variables file found
-func (i *Inventory) GetVariablesForGroup(group string) string {
+// GetVariablesForGroup returns the paths of the group's variables file and the
+// files in its `variables.d` directory, or nil if no group/variables found
+func (i *Inventory) GetVariablesForGroup(group string) []string {
 	if g, found := i.GetGroup(group); found {
-		return g.variables
+		return variableFiles(g.variables, g.variablesDir)
 	}
 
-	return ""
+	return nil
 }
@@ -0,0 +1,397 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/viper"
+)
+
+// testInventoryPath is the path test inventories are identified by. They're
+// parsed from memory, so nothing is read from this path.
+const testInventoryPath = "/inventory"
+
+// inventoryFixture is an in-memory inventory tree for tests, mapping the
+// paths of files relative to the inventory root (ie, `hosts/foo/modules`) to
+// their contents. Directories are implied by the files within them.
+type inventoryFixture map[string]string
+
+// load parses the fixture into an inventory for the provided hostname.
+func (f inventoryFixture) load(t *testing.T, hostname string) *Inventory {
+	t.Helper()
+
+	fsys := make(fstest.MapFS, len(f))
+	for path, content := range f {
+		fsys[path] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	inv := NewInventoryWithFS(fsys, testInventoryPath, hostname)
+	inv.Reload(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	return inv
+}
+
+// setConfig sets the viper config keys for the duration of the test.
+func setConfig(t *testing.T, settings map[string]any) {
+	t.Helper()
+	t.Cleanup(viper.Reset)
+
+	for k, v := range settings {
+		viper.Set(k, v)
+	}
+}
+
+// assertIDs asserts that the inventory components have exactly the wanted
+// names, in any order. Components are compared by the base name of their ID,
+// as some (ie, roles) use their path as their ID.
+func assertIDs[T fmt.Stringer](t *testing.T, what string, got []T, want ...string) {
+	t.Helper()
+
+	ids := make([]string, 0, len(got))
+	for _, item := range got {
+		ids = append(ids, filepath.Base(item.String()))
+	}
+	slices.Sort(ids)
+	want = slices.Clone(want)
+	slices.Sort(want)
+
+	if !slices.Equal(ids, want) {
+		t.Errorf("%s = %q, want %q", what, ids, want)
+	}
+}
+
+// assertPaths asserts that the paths are exactly the wanted paths relative
+// to the inventory root, in order.
+func assertPaths(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+
+	rel := make([]string, 0, len(got))
+	for _, path := range got {
+		r, err := filepath.Rel(testInventoryPath, path)
+		if err != nil {
+			t.Fatalf("%s: path %q not in inventory: %v", what, path, err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+
+	if !slices.Equal(rel, want) {
+		t.Errorf("%s = %q, want %q", what, rel, want)
+	}
+}
+
+// testFixture is a small inventory exercising the ways hosts are enrolled and
+// modules are assigned.
+var testFixture = inventoryFixture{
+	"modules/base/apply":                 "true",
+	"modules/web/apply":                  "true",
+	"modules/db/apply":                   "true",
+	"modules/metrics/apply":              "true",
+	"modules/debug/apply":                "true",
+	"modules/disabled/apply":             "true",
+	"modules/disabled/" + DisabledMarker: "",
+
+	"roles/webserver/modules":   "web\nmetrics\n",
+	"roles/webserver/variables": "ROLE=webserver",
+	"roles/database/modules":    "db\nmetrics\nmissing\n",
+	"roles/database/variables":  "ROLE=database",
+
+	"groups/web/glob":              "web-*",
+	"groups/web/roles":             "webserver",
+	"groups/web/variables":         "GROUP=web",
+	"groups/db/regex":              "^db[0-9]+$",
+	"groups/db/roles":              "database",
+	"groups/db/modules":            "debug",
+	"groups/db/variables":          "GROUP=db",
+	"groups/db/variables.d/10-foo": "FOO=db",
+
+	"hosts/_default/modules":   "base",
+	"hosts/_default/variables": "DEFAULT=true",
+
+	"hosts/web-1/modules":   "debug\nweb\n",
+	"hosts/web-1/variables": "HOST=web-1",
+
+	"hosts/standalone/roles":   "database",
+	"hosts/standalone/modules": "disabled",
+
+	"hosts/retired/modules":           "base",
+	"hosts/retired/" + DisabledMarker: "",
+}
+
+func TestEnrollment(t *testing.T) {
+	tests := []struct {
+		name            string
+		hostname        string
+		config          map[string]any
+		enrolled        bool
+		groups          []string
+		hostEntryExists bool
+	}{
+		{
+			name:            "host entry and glob group",
+			hostname:        "web-1",
+			enrolled:        true,
+			groups:          []string{"web"},
+			hostEntryExists: true,
+		},
+		{
+			name:     "glob group only",
+			hostname: "web-2",
+			enrolled: true,
+			groups:   []string{"web"},
+		},
+		{
+			name:     "regex group only",
+			hostname: "db12",
+			enrolled: true,
+			groups:   []string{"db"},
+		},
+		{
+			name:     "regex must match whole pattern",
+			hostname: "db12-replica",
+		},
+		{
+			name:            "host entry only",
+			hostname:        "standalone",
+			enrolled:        true,
+			hostEntryExists: true,
+		},
+		{
+			name:     "disabled host",
+			hostname: "retired",
+		},
+		{
+			name:     "unknown host",
+			hostname: "unknown",
+		},
+		{
+			name:     "hostnames are case sensitive by default",
+			hostname: "WEB-1",
+		},
+		{
+			name:            "case insensitive hostnames",
+			hostname:        "WEB-1",
+			config:          map[string]any{"inventory.case-insensitive-hostnames": true},
+			enrolled:        true,
+			groups:          []string{"web"},
+			hostEntryExists: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfig(t, tt.config)
+			inv := testFixture.load(t, tt.hostname)
+
+			if got := inv.IsEnrolled(); got != tt.enrolled {
+				t.Errorf("IsEnrolled() = %t, want %t", got, tt.enrolled)
+			}
+			if _, found := inv.GetHost(tt.hostname); found != tt.hostEntryExists {
+				t.Errorf("GetHost() found = %t, want %t", found, tt.hostEntryExists)
+			}
+			assertIDs(t, "GetGroupsForSelf()", inv.GetGroupsForSelf(), tt.groups...)
+		})
+	}
+}
+
+func TestModuleResolution(t *testing.T) {
+	tests := []struct {
+		hostname string
+		roles    []string
+		modules  []string
+	}{
+		{
+			// host modules, group roles, and the default host,
+			// deduplicated
+			hostname: "web-1",
+			roles:    []string{"webserver"},
+			modules:  []string{"base", "debug", "web", "metrics"},
+		},
+		{
+			hostname: "web-2",
+			roles:    []string{"webserver"},
+			modules:  []string{"base", "web", "metrics"},
+		},
+		{
+			// group modules, and missing modules are skipped
+			hostname: "db1",
+			roles:    []string{"database"},
+			modules:  []string{"base", "db", "metrics", "debug"},
+		},
+		{
+			// disabled modules are skipped
+			hostname: "standalone",
+			roles:    []string{"database"},
+			modules:  []string{"base", "db", "metrics"},
+		},
+		{
+			hostname: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			setConfig(t, nil)
+			inv := testFixture.load(t, tt.hostname)
+
+			assertIDs(t, "GetRolesForSelf()", inv.GetRolesForSelf(), tt.roles...)
+			assertIDs(t, "GetModulesForSelf()", inv.GetModulesForSelf(), tt.modules...)
+		})
+	}
+}
+
+func TestVariablePrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		hostname   string
+		precedence string
+		want       []string
+	}{
+		{
+			name:     "host wins by default",
+			hostname: "web-1",
+			want: []string{
+				"hosts/_default/variables",
+				"roles/webserver/variables",
+				"groups/web/variables",
+				"hosts/web-1/variables",
+			},
+		},
+		{
+			name:       "role precedence",
+			hostname:   "web-1",
+			precedence: PrecedenceRole,
+			want: []string{
+				"hosts/_default/variables",
+				"hosts/web-1/variables",
+				"groups/web/variables",
+				"roles/webserver/variables",
+			},
+		},
+		{
+			name:     "variables.d follows its component's variables",
+			hostname: "db1",
+			want: []string{
+				"hosts/_default/variables",
+				"roles/database/variables",
+				"groups/db/variables",
+				"groups/db/variables.d/10-foo",
+			},
+		},
+		{
+			name:       "variables.d stays with its component with role precedence",
+			hostname:   "db1",
+			precedence: PrecedenceRole,
+			want: []string{
+				"hosts/_default/variables",
+				"groups/db/variables",
+				"groups/db/variables.d/10-foo",
+				"roles/database/variables",
+			},
+		},
+		{
+			name:     "unenrolled hosts get no variables",
+			hostname: "unknown",
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfig(t, map[string]any{"inventory.variable-precedence": tt.precedence})
+			inv := testFixture.load(t, tt.hostname)
+
+			assertPaths(t, "GetVariablesForSelf()", inv.GetVariablesForSelf(), tt.want...)
+		})
+	}
+}
+
+func TestFilterDuplicatesKeepsOrder(t *testing.T) {
+	mods := []Module{{ID: "c"}, {ID: "a"}, {ID: "c"}, {ID: "b"}, {ID: "a"}}
+	gotMods := filterDuplicateModules(mods)
+	if want := []Module{{ID: "c"}, {ID: "a"}, {ID: "b"}}; !slices.EqualFunc(gotMods, want, func(a, b Module) bool { return a.ID == b.ID }) {
+		t.Errorf("filterDuplicateModules() = %v, want %v", gotMods, want)
+	}
+
+	roles := []Role{{id: "z"}, {id: "y"}, {id: "z"}, {id: "x"}}
+	gotRoles := filterDuplicateRoles(roles)
+	if want := []Role{{id: "z"}, {id: "y"}, {id: "x"}}; !slices.EqualFunc(gotRoles, want, func(a, b Role) bool { return a.id == b.id }) {
+		t.Errorf("filterDuplicateRoles() = %v, want %v", gotRoles, want)
+	}
+
+	// modules for a host are deterministically ordered: roles, groups,
+	// the default host, then the host itself
+	setConfig(t, nil)
+	inv := testFixture.load(t, "web-1")
+	for range 10 {
+		var ids []string
+		for _, m := range inv.GetModulesForSelf() {
+			ids = append(ids, m.String())
+		}
+		if want := []string{"web", "metrics", "base", "debug"}; !slices.Equal(ids, want) {
+			t.Fatalf("GetModulesForSelf() = %q, want %q", ids, want)
+		}
+	}
+}
+
+func TestTemplatePrecedence(t *testing.T) {
+	fixture := inventoryFixture{
+		"modules/web/apply": "true",
+
+		"roles/webserver/modules":              "web",
+		"roles/webserver/templates/common.tpl": "role",
+		"roles/webserver/templates/role.tpl":   "role",
+
+		"groups/web/glob":                 "web-*",
+		"groups/web/roles":                "webserver",
+		"groups/web/templates/common.tpl": "group",
+		"groups/web/templates/group.tpl":  "group",
+
+		"hosts/_default/templates/common.tpl":  "default",
+		"hosts/_default/templates/default.tpl": "default",
+
+		"hosts/web-1/templates/common.tpl": "host",
+	}
+
+	tests := []struct {
+		name       string
+		precedence string
+		want       []string
+	}{
+		{
+			name: "host wins by default",
+			want: []string{
+				"hosts/_default/templates/default.tpl",
+				"roles/webserver/templates/role.tpl",
+				"groups/web/templates/group.tpl",
+				"hosts/web-1/templates/common.tpl",
+			},
+		},
+		{
+			name:       "role wins with role precedence",
+			precedence: PrecedenceRole,
+			want: []string{
+				"hosts/_default/templates/default.tpl",
+				"groups/web/templates/group.tpl",
+				"roles/webserver/templates/role.tpl",
+				"roles/webserver/templates/common.tpl",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfig(t, map[string]any{"inventory.variable-precedence": tt.precedence})
+			inv := fixture.load(t, "web-1")
+
+			// only one template per file name, as they're
+			// associated by file name when parsed
+			assertPaths(t, "GetTemplatesForSelf()", inv.GetTemplatesForSelf(), tt.want...)
+		})
+	}
+}
@@ -13,14 +13,15 @@ var (
 		"hostname":       "unknown",
 		"enrolled":       "false",
 		"inventory_path": "unknown",
+		"commit":         "unknown",
 	}
 
 	metricMangoInventoryInfo = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "mango_inventory_info",
-			Help: "A metric with a constant '1' value with labels for information about the mango inventory",
+			Help: "A metric with a constant '1' value with labels for information about the mango inventory, including the abbreviated commit SHA if the inventory is a git repository",
 		},
-		[]string{"hostname", "enrolled", "inventory_path"},
+		[]string{"hostname", "enrolled", "inventory_path", "commit"},
 	)
 
 	metricInventory = promauto.NewGaugeVec(
@@ -62,4 +63,67 @@ var (
 		},
 		commonMetricLabels,
 	)
+
+	metricInventoryGroupHosts = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_group_hosts",
+			Help: "Number of hosts defined in the inventory that are members of each group",
+		},
+		[]string{"inventory", "group"},
+	)
+
+	metricInventoryModulesPerHostAverage = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_modules_per_host_average",
+			Help: "Average number of modules applied to each host defined in the inventory, including modules from roles and groups",
+		},
+		[]string{"inventory"},
+	)
+
+	metricInventoryOrphanedModules = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_orphaned_modules",
+			Help: "Number of modules in the inventory that aren't referenced by any host, role, or group",
+		},
+		[]string{"inventory"},
+	)
+
+	metricInventoryFilesTotal = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_files_total",
+			Help: "Total number of files under the inventory path, excluding the `.git` directory",
+		},
+		[]string{"inventory"},
+	)
+
+	metricInventoryBytesTotal = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_bytes_total",
+			Help: "Total size in bytes of the files under the inventory path, excluding the `.git` directory",
+		},
+		[]string{"inventory"},
+	)
+
+	metricInventoryReloadSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_reload_success",
+			Help: "Whether the last full reload of the inventory succeeded (1) or any component failed to sync/parse (0)",
+		},
+		[]string{"inventory"},
+	)
+
+	metricInventoryLastReloadTimestamp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_inventory_last_reload_timestamp",
+			Help: "Timestamp of the last full reload of the inventory, in seconds since the epoch",
+		},
+		[]string{"inventory"},
+	)
+
+	metricInventorySourceSyncFailedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_inventory_source_sync_failed_total",
+			Help: "Total number of times the mango inventory has failed to sync from the configured remote inventory source",
+		},
+	)
 )
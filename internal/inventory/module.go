@@ -2,13 +2,26 @@ package inventory
 
 import (
 	"context"
+	"io/fs"
 	"log/slog"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tjhop/mango/pkg/utils"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ModuleStatePresent is the default state of a module, meaning the
+	// module's configuration should be ensured
+	ModuleStatePresent = "present"
+	// ModuleStateAbsent is the state of a module whose configuration should
+	// be removed
+	ModuleStateAbsent = "absent"
 )
 
 var (
@@ -17,24 +30,74 @@ var (
 )
 
 // Module contains fields that represent a single module in the inventory.
-// - ID: string idenitfying the module (generally the file path to the module)
+// - ID: string identifying the module, the module's inventory-relative name
+// (ie, `foo` for `modules/foo`). This is the canonical identity of the module,
+// used for lookups, dependencies, deduplication, and metrics labels.
+// - Path: path to the module's directory
 // - Apply: path to apply script for the module
 // - Variables: path to variables file for the module, if present
+// - VariablesDir: paths of the files in the module's `variables.d` directory,
+// if present
 // - Requires: path to requirements file for the module, if present
 // - Test: path to test script to check module's application status
 // - TemplateFiles: slice of paths of user defined template files
+// - SkipApplyOnTestSuccess: module level override for whether the apply script
+// should be skipped if the test script succeeds. nil if not set for the module.
+// - Env: slice of literal environment variables in `key=value` form from the
+// module's `env` file, if present. These are not templated.
+// - Limits: path to resource limits file for the module, if present
+// - WorkDir: path to the (templated) file containing the working directory to
+// run the module's scripts in, if present
+// - Stdin: path to the (templated) file whose contents are provided as stdin to
+// the module's scripts, if present
+// - Become: path to the file containing the `user[:group]` to run the module's
+// scripts as, if present
+// - SupportedOS: slice of operating systems/distributions the module supports,
+// from the module's `supported-os` file, if present. Empty if unrestricted.
+// - SupportedArch: slice of CPU architectures the module supports, from the
+// module's `supported-arch` file, if present. Empty if unrestricted.
+// - Meta: descriptive metadata from the module's `meta` file, if present. nil
+// if not set for the module.
+// - State: the desired state of the module from its `state` file, one of
+// `present` or `absent`. Defaults to `present`. Provided to the module's
+// scripts as `MANGO_STATE`, so a module can handle both setup and teardown.
 type Module struct {
-	ID            string
-	Apply         string
-	Variables     string
-	Test          string
-	Requires      string
-	TemplateFiles []string
+	ID                     string
+	Path                   string
+	Apply                  string
+	Variables              string
+	VariablesDir           []string
+	Test                   string
+	Requires               string
+	TemplateFiles          []string
+	SkipApplyOnTestSuccess *bool
+	Env                    []string
+	Limits                 string
+	WorkDir                string
+	Stdin                  string
+	Become                 string
+	SupportedOS            []string
+	SupportedArch          []string
+	Meta                   *ModuleMeta
+	State                  string
+}
+
+// ModuleMeta contains optional descriptive metadata about a module, parsed
+// from the YAML `meta` file in the module's directory. It is purely
+// informational, and doesn't affect how the module is run.
+type ModuleMeta struct {
+	Description string `yaml:"description" json:"description,omitempty"`
+	Author      string `yaml:"author" json:"author,omitempty"`
+	Version     string `yaml:"version" json:"version,omitempty"`
 }
 
 // String is a stringer to return the module ID
 func (m Module) String() string { return m.ID }
 
+// VariableFiles returns the paths of the module's variables file and the files
+// in its `variables.d` directory, in the order they are sourced
+func (m Module) VariableFiles() []string { return variableFiles(m.Variables, m.VariablesDir) }
+
 // ParseModules looks for modules in the inventory's `modules/` folder. It looks for
 // folders within this directory, and then parses each directory into a Module struct.
 // Each module folder is expected to contain files for `apply`, `variables`, and `test`,
@@ -52,7 +115,7 @@ func (i *Inventory) ParseModules(ctx context.Context, logger *slog.Logger) error
 	)
 
 	path := filepath.Join(i.inventoryPath, "modules")
-	modDirs, err := utils.GetFilesInDirectory(path)
+	modDirs, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(path))
 	if err != nil {
 		iLogger.LogAttrs(
 			ctx,
@@ -73,7 +136,17 @@ func (i *Inventory) ParseModules(ctx context.Context, logger *slog.Logger) error
 	for _, modDir := range modDirs {
 		if modDir.IsDir() && !utils.IsHidden(modDir.Name()) {
 			modPath := filepath.Join(path, modDir.Name())
-			modFiles, err := utils.GetFilesInDirectory(modPath)
+			if i.isDisabled(modPath) {
+				iLogger.LogAttrs(
+					ctx,
+					slog.LevelInfo,
+					"Skipping disabled module while parsing inventory",
+					slog.String("path", modPath),
+				)
+				continue
+			}
+
+			modFiles, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(modPath))
 			if err != nil {
 				iLogger.LogAttrs(
 					ctx,
@@ -89,9 +162,13 @@ func (i *Inventory) ParseModules(ctx context.Context, logger *slog.Logger) error
 				return err
 			}
 
-			mod := Module{ID: modPath}
+			mod := Module{ID: modDir.Name(), Path: modPath, State: ModuleStatePresent}
 
 			for _, modFile := range modFiles {
+				if modFile.IsDir() && modFile.Name() == VariablesDirName {
+					mod.VariablesDir = i.parseVariablesDir(ctx, iLogger, filepath.Join(modPath, VariablesDirName))
+				}
+
 				if modFile.IsDir() && modFile.Name() == "templates" {
 					templatedir := filepath.Join(modPath, "templates")
 
@@ -102,7 +179,7 @@ func (i *Inventory) ParseModules(ctx context.Context, logger *slog.Logger) error
 					// > ErrBadPattern, when pattern is
 					// > malformed.
 					// ...I'm making the pattern. I know it's not malformed.
-					matchedTpls, _ := filepath.Glob(filepath.Join(templatedir, "*.tpl"))
+					matchedTpls, _ := i.glob(templatedir, "*.tpl")
 					mod.TemplateFiles = matchedTpls
 				}
 
@@ -117,6 +194,167 @@ func (i *Inventory) ParseModules(ctx context.Context, logger *slog.Logger) error
 						mod.Variables = filepath.Join(modPath, "variables")
 					case "requires":
 						mod.Requires = filepath.Join(modPath, "requires")
+					case "env":
+						var env []string
+						envPath := filepath.Join(modPath, "env")
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(envPath))
+
+						for line := range lines {
+							if line.Err != nil {
+								iLogger.LogAttrs(
+									ctx,
+									slog.LevelError,
+									"Failed to read env for module",
+									slog.String("err", line.Err.Error()),
+									slog.String("path", envPath),
+								)
+								continue
+							}
+
+							text := strings.TrimSpace(line.Text)
+							if text == "" || strings.HasPrefix(text, "#") {
+								continue
+							}
+
+							if !strings.Contains(text, "=") {
+								iLogger.LogAttrs(
+									ctx,
+									slog.LevelWarn,
+									"Skipping invalid line in module env file, expected `key=value` format",
+									slog.String("line", text),
+									slog.String("path", envPath),
+								)
+								continue
+							}
+
+							env = append(env, text)
+						}
+
+						mod.Env = env
+					case "limits":
+						mod.Limits = filepath.Join(modPath, "limits")
+					case "workdir":
+						mod.WorkDir = filepath.Join(modPath, "workdir")
+					case "stdin":
+						mod.Stdin = filepath.Join(modPath, "stdin")
+					case "become":
+						mod.Become = filepath.Join(modPath, "become")
+					case "supported-os", "supported-arch":
+						var platforms []string
+						platformPath := filepath.Join(modPath, fileName)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(platformPath))
+
+						for line := range lines {
+							if line.Err != nil {
+								iLogger.LogAttrs(
+									ctx,
+									slog.LevelError,
+									"Failed to read supported platforms for module",
+									slog.String("err", line.Err.Error()),
+									slog.String("path", platformPath),
+								)
+								continue
+							}
+
+							text := strings.ToLower(strings.TrimSpace(line.Text))
+							if text == "" || strings.HasPrefix(text, "#") {
+								continue
+							}
+
+							platforms = append(platforms, text)
+						}
+
+						if fileName == "supported-os" {
+							mod.SupportedOS = platforms
+						} else {
+							mod.SupportedArch = platforms
+						}
+					case "meta":
+						metaPath := filepath.Join(modPath, "meta")
+						content, err := fs.ReadFile(i.fsys, i.fsPath(metaPath))
+						if err != nil {
+							iLogger.LogAttrs(
+								ctx,
+								slog.LevelError,
+								"Failed to read meta file for module",
+								slog.String("err", err.Error()),
+								slog.String("path", metaPath),
+							)
+							continue
+						}
+
+						var meta ModuleMeta
+						if err := yaml.Unmarshal(content, &meta); err != nil {
+							iLogger.LogAttrs(
+								ctx,
+								slog.LevelError,
+								"Failed to parse meta file for module",
+								slog.String("err", err.Error()),
+								slog.String("path", metaPath),
+							)
+							continue
+						}
+
+						mod.Meta = &meta
+					case "state":
+						statePath := filepath.Join(modPath, "state")
+						content, err := fs.ReadFile(i.fsys, i.fsPath(statePath))
+						if err != nil {
+							iLogger.LogAttrs(
+								ctx,
+								slog.LevelError,
+								"Failed to read state file for module",
+								slog.String("err", err.Error()),
+								slog.String("path", statePath),
+							)
+							continue
+						}
+
+						state := strings.ToLower(strings.TrimSpace(string(content)))
+						switch state {
+						case ModuleStatePresent, ModuleStateAbsent:
+							mod.State = state
+						default:
+							iLogger.LogAttrs(
+								ctx,
+								slog.LevelError,
+								"Invalid state in state file for module, expected one of [present, absent]",
+								slog.String("state", state),
+								slog.String("path", statePath),
+							)
+						}
+					case "skip-apply-on-test-success":
+						// an empty marker file enables skipping, otherwise
+						// the file's contents are parsed as a boolean
+						skipPath := filepath.Join(modPath, "skip-apply-on-test-success")
+						skip := true
+						content, err := fs.ReadFile(i.fsys, i.fsPath(skipPath))
+						if err != nil {
+							iLogger.LogAttrs(
+								ctx,
+								slog.LevelError,
+								"Failed to read skip-apply-on-test-success file for module",
+								slog.String("err", err.Error()),
+								slog.String("path", skipPath),
+							)
+							continue
+						}
+
+						if trimmed := strings.TrimSpace(string(content)); trimmed != "" {
+							skip, err = strconv.ParseBool(trimmed)
+							if err != nil {
+								iLogger.LogAttrs(
+									ctx,
+									slog.LevelError,
+									"Failed to parse skip-apply-on-test-success file for module",
+									slog.String("err", err.Error()),
+									slog.String("path", skipPath),
+								)
+								continue
+							}
+						}
+
+						mod.SkipApplyOnTestSuccess = &skip
 					default:
 						iLogger.LogAttrs(
 							ctx,
@@ -15,11 +15,13 @@ import (
 // - ID: string idenitfying the role (generally the file path to the role)
 // - Modules: a []string of module names that satisfy this role
 // - variables: path to the variables file for this role, if present
+// - variablesDir: paths of the files in the role's `variables.d` directory, if present
 // - templateFiles: slice of paths of user defined template files
 type Role struct {
 	id            string
 	modules       []string
 	variables     string
+	variablesDir  []string
 	templateFiles []string
 }
 
@@ -42,7 +44,7 @@ func (i *Inventory) ParseRoles(ctx context.Context, logger *slog.Logger) error {
 	)
 
 	path := filepath.Join(i.inventoryPath, "roles")
-	roleDirs, err := utils.GetFilesInDirectory(path)
+	roleDirs, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(path))
 	if err != nil {
 		iLogger.LogAttrs(
 			ctx,
@@ -63,7 +65,17 @@ func (i *Inventory) ParseRoles(ctx context.Context, logger *slog.Logger) error {
 	for _, roleDir := range roleDirs {
 		if roleDir.IsDir() && !utils.IsHidden(roleDir.Name()) {
 			rolePath := filepath.Join(path, roleDir.Name())
-			roleFiles, err := utils.GetFilesInDirectory(rolePath)
+			if i.isDisabled(rolePath) {
+				iLogger.LogAttrs(
+					ctx,
+					slog.LevelInfo,
+					"Skipping disabled role while parsing inventory",
+					slog.String("path", rolePath),
+				)
+				continue
+			}
+
+			roleFiles, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(rolePath))
 			if err != nil {
 				iLogger.LogAttrs(
 					ctx,
@@ -82,6 +94,10 @@ func (i *Inventory) ParseRoles(ctx context.Context, logger *slog.Logger) error {
 			role := Role{id: rolePath}
 
 			for _, roleFile := range roleFiles {
+				if roleFile.IsDir() && roleFile.Name() == VariablesDirName {
+					role.variablesDir = i.parseVariablesDir(ctx, iLogger, filepath.Join(rolePath, VariablesDirName))
+				}
+
 				if roleFile.IsDir() && roleFile.Name() == "templates" {
 					templatedir := filepath.Join(rolePath, "templates")
 
@@ -92,7 +108,7 @@ func (i *Inventory) ParseRoles(ctx context.Context, logger *slog.Logger) error {
 					// > ErrBadPattern, when pattern is
 					// > malformed.
 					// ...I'm making the pattern. I know it's not malformed.
-					matchedTpls, _ := filepath.Glob(filepath.Join(templatedir, "*.tpl"))
+					matchedTpls, _ := i.glob(templatedir, "*.tpl")
 					role.templateFiles = matchedTpls
 				}
 
@@ -102,7 +118,7 @@ func (i *Inventory) ParseRoles(ctx context.Context, logger *slog.Logger) error {
 					case "modules":
 						var mods []string
 						modPath := filepath.Join(rolePath, "modules")
-						lines := utils.ReadFileLines(modPath)
+						lines := utils.ReadFileLinesFS(i.fsys, i.fsPath(modPath))
 
 						for line := range lines {
 							if line.Err != nil {
@@ -0,0 +1,187 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// SourceTypeGit syncs the inventory from a git repository
+	SourceTypeGit = "git"
+	// SourceTypeRsync syncs the inventory from an rsync/SSH path
+	SourceTypeRsync = "rsync"
+)
+
+// sourceType returns the type of remote inventory source for the given
+// source. Sources with an `rsync://` scheme or in `[user@]host:path` form are
+// synced with rsync, anything else that looks like a git remote is cloned
+// with git. An explicit `inventory.source-type` takes precedence.
+func sourceType(source string) string {
+	if t := strings.ToLower(viper.GetString("inventory.source-type")); t != "" {
+		return t
+	}
+
+	switch {
+	case strings.HasPrefix(source, "rsync://"):
+		return SourceTypeRsync
+	case strings.HasSuffix(source, ".git"),
+		strings.Contains(source, "://"):
+		return SourceTypeGit
+	default:
+		return SourceTypeRsync
+	}
+}
+
+// syncSource updates the inventory path from the configured remote inventory
+// source, if one is configured. If the sync fails, the last successfully
+// synced copy of the inventory at the inventory path is left in place so that
+// it can continue to be used.
+func (i *Inventory) syncSource(ctx context.Context, logger *slog.Logger) error {
+	source := viper.GetString("inventory.source")
+	if source == "" {
+		return nil
+	}
+
+	sLogger := logger.With(
+		slog.Group(
+			"source",
+			slog.String("url", source),
+			slog.String("type", sourceType(source)),
+		),
+	)
+	sLogger.LogAttrs(
+		ctx,
+		slog.LevelDebug,
+		"Syncing inventory from remote source",
+	)
+
+	var err error
+	switch sourceType(source) {
+	case SourceTypeGit:
+		err = syncGit(ctx, source, i.inventoryPath)
+	case SourceTypeRsync:
+		err = syncRsync(ctx, source, i.inventoryPath)
+	default:
+		err = fmt.Errorf("Unsupported inventory source type: %s", sourceType(source))
+	}
+
+	if err != nil {
+		metricInventorySourceSyncFailedTotal.Inc()
+		return err
+	}
+
+	sLogger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"Synced inventory from remote source",
+	)
+
+	return nil
+}
+
+// sshCommand returns the ssh command to use for the configured SSH key, if
+// any.
+func sshCommand() string {
+	key := viper.GetString("inventory.source-ssh-key")
+	if key == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o BatchMode=yes", shellQuote(key))
+}
+
+// shellQuote quotes the string as a single argument for both `sh` (as used
+// for `GIT_SSH_COMMAND`) and rsync's `-e` parsing, which doesn't support
+// backslash escapes, so embedded single quotes are wrapped in double quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+func syncGit(ctx context.Context, source, path string) error {
+	// the token is passed through the environment rather than with `-c` on
+	// the command line, so that it isn't exposed in the process list
+	var authEnv []string
+	if tokenFile := viper.GetString("inventory.source-token-file"); tokenFile != "" {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read inventory source token file: %v", err)
+		}
+
+		authEnv = append(authEnv,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Bearer "+strings.TrimSpace(string(token)),
+		)
+	}
+
+	ref := viper.GetString("inventory.source-ref")
+
+	// clone fresh if we don't have a copy yet, otherwise fetch and
+	// hard reset, so that a failed fetch leaves the last good copy alone
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		args := []string{"clone", "--depth", "1"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		args = append(args, "--", source, path)
+
+		return runSyncCommand(ctx, authEnv, "git", args...)
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := runSyncCommand(ctx, authEnv, "git", "-C", path, "fetch", "--depth", "1", "--", source, ref); err != nil {
+		return err
+	}
+
+	if err := runSyncCommand(ctx, nil, "git", "-C", path, "reset", "--hard", "FETCH_HEAD"); err != nil {
+		return err
+	}
+
+	return runSyncCommand(ctx, nil, "git", "-C", path, "clean", "-fdx")
+}
+
+func syncRsync(ctx context.Context, source, path string) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("Failed to create inventory directory: %v", err)
+	}
+
+	// delay updates/deletes until the end of the transfer, so that a failed
+	// sync doesn't leave a half updated inventory behind
+	args := []string{"-a", "--delete", "--delete-delay", "--delay-updates"}
+	if ssh := sshCommand(); ssh != "" {
+		args = append(args, "-e", ssh)
+	}
+	args = append(args, strings.TrimSuffix(source, "/")+"/", path)
+
+	return runSyncCommand(ctx, nil, "rsync", args...)
+}
+
+// runSyncCommand runs the sync command, with the extra environment variables
+// appended to mango's environment.
+func runSyncCommand(ctx context.Context, env []string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	if ssh := sshCommand(); ssh != "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+ssh)
+	}
+	// never prompt for credentials, fail instead
+	cmd.Env = append(cmd.Env, "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Failed to run %s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
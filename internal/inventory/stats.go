@@ -0,0 +1,167 @@
+package inventory
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// updateStatsMetrics updates the aggregate metrics about the inventory's
+// health, as opposed to the per component counts updated while parsing:
+// - the number of hosts defined in the inventory that are members of each group
+// - the average number of modules applied to each host defined in the inventory
+// - the number of orphaned modules, that aren't referenced by any host, role,
+// or group
+func (i *Inventory) updateStatsMetrics() {
+	inventoryLabel := prometheus.Labels{"inventory": i.inventoryPath}
+
+	// groups may have been removed since the last reload, so clear out old
+	// series before setting new ones
+	metricInventoryGroupHosts.DeletePartialMatch(inventoryLabel)
+	for _, g := range i.groups {
+		hosts := 0
+		for _, h := range i.hosts {
+			if g.IsHostEnrolled(h.String()) {
+				hosts++
+			}
+		}
+		metricInventoryGroupHosts.With(prometheus.Labels{"inventory": i.inventoryPath, "group": g.String()}).Set(float64(hosts))
+	}
+
+	var avgModules float64
+	if len(i.hosts) > 0 {
+		modules := 0
+		for _, h := range i.hosts {
+			modules += len(i.GetModulesForHost(h.String()))
+		}
+		avgModules = float64(modules) / float64(len(i.hosts))
+	}
+	metricInventoryModulesPerHostAverage.With(inventoryLabel).Set(avgModules)
+
+	metricInventoryOrphanedModules.With(inventoryLabel).Set(float64(len(i.GetOrphanedModules())))
+}
+
+// updateSizeMetrics updates the metrics for the total number of files and
+// bytes under the inventory path, to help catch inventory bloat. The `.git`
+// directory is skipped, since it isn't parsed.
+func (i *Inventory) updateSizeMetrics(ctx context.Context, logger *slog.Logger) {
+	var files, bytes int64
+	err := fs.WalkDir(i.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		bytes += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Failed to calculate inventory size",
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	inventoryLabel := prometheus.Labels{"inventory": i.inventoryPath}
+	metricInventoryFilesTotal.With(inventoryLabel).Set(float64(files))
+	metricInventoryBytesTotal.With(inventoryLabel).Set(float64(bytes))
+}
+
+// GetOrphanedModules returns a slice of the Modules that aren't referenced by
+// any host, role, or group in the inventory.
+func (i *Inventory) GetOrphanedModules() []Module {
+	referenced := make(map[string]struct{})
+	for _, h := range i.hostsWithDefault() {
+		for _, m := range h.modules {
+			referenced[m] = struct{}{}
+		}
+	}
+	for _, r := range i.roles {
+		for _, m := range r.modules {
+			referenced[m] = struct{}{}
+		}
+	}
+	for _, g := range i.groups {
+		for _, m := range g.modules {
+			referenced[m] = struct{}{}
+		}
+	}
+
+	var orphaned []Module
+	for _, m := range i.modules {
+		if _, found := referenced[m.ID]; !found {
+			orphaned = append(orphaned, m)
+		}
+	}
+
+	return orphaned
+}
+
+// GetOrphanedRoles returns a slice of the Roles that aren't referenced by any
+// host or group in the inventory.
+func (i *Inventory) GetOrphanedRoles() []Role {
+	referenced := make(map[string]struct{})
+	for _, h := range i.hostsWithDefault() {
+		for _, r := range h.roles {
+			referenced[r] = struct{}{}
+		}
+	}
+	for _, g := range i.groups {
+		for _, r := range g.roles {
+			referenced[r] = struct{}{}
+		}
+	}
+
+	var orphaned []Role
+	for _, r := range i.roles {
+		if _, found := referenced[filepath.Base(r.id)]; !found {
+			orphaned = append(orphaned, r)
+		}
+	}
+
+	return orphaned
+}
+
+// GetEmptyGroups returns a slice of the Groups whose globs/patterns don't match
+// any of the hosts defined in the inventory. Note that this is distinct from
+// whether a group matches the running system, which may not be defined in the
+// inventory.
+func (i *Inventory) GetEmptyGroups() []Group {
+	var empty []Group
+	for _, g := range i.groups {
+		if !slices.ContainsFunc(i.hosts, func(h Host) bool { return g.IsHostEnrolled(h.String()) }) {
+			empty = append(empty, g)
+		}
+	}
+
+	return empty
+}
+
+// hostsWithDefault returns the hosts defined in the inventory, including the
+// `_default` host entry if present.
+func (i *Inventory) hostsWithDefault() []Host {
+	if i.defaultHost == nil {
+		return i.hosts
+	}
+
+	return append(slices.Clone(i.hosts), *i.defaultHost)
+}
@@ -0,0 +1,53 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+// VariablesDirName is the name of the drop-in directory of variables files for
+// a host, role, group, or module. Files in the directory are sourced in
+// lexical order after the component's `variables` file (if any), so later
+// files override earlier ones.
+const VariablesDirName = "variables.d"
+
+// parseVariablesDir returns the paths of the files in the provided
+// `variables.d` directory, in lexical order. Hidden files and subdirectories
+// are skipped.
+func (i *Inventory) parseVariablesDir(ctx context.Context, logger *slog.Logger, dir string) []string {
+	files, err := utils.GetFilesInDirectoryFS(i.fsys, i.fsPath(dir))
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to read variables directory",
+			slog.String("err", err.Error()),
+			slog.String("path", dir),
+		)
+		return nil
+	}
+
+	// `fs.ReadDir` returns entries sorted by file name
+	var paths []string
+	for _, f := range files {
+		if !f.IsDir() && !utils.IsHidden(f.Name()) {
+			paths = append(paths, filepath.Join(dir, f.Name()))
+		}
+	}
+
+	return paths
+}
+
+// variableFiles returns the provided variables file (if set) followed by the
+// files from the component's `variables.d` directory.
+func variableFiles(variables string, variablesDir []string) []string {
+	var files []string
+	if variables != "" {
+		files = append(files, variables)
+	}
+
+	return append(files, variablesDir...)
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/config"
+)
+
+// moduleBreaker tracks the consecutive failures of a module across runs, for
+// the module circuit breaker. Once the module has failed
+// `manager.circuit-breaker-threshold` times in a row, the circuit is opened
+// and the module is skipped until `manager.circuit-breaker-cooldown` has
+// passed, after which it's retried once. Success closes the circuit, and
+// another failure re-opens it for another cooldown.
+type moduleBreaker struct {
+	failures int
+	openedAt time.Time // zero if the circuit is closed
+}
+
+// circuitOpen returns true if the module's circuit breaker is open, and the
+// module should be skipped for this run. The circuit breaker is disabled if
+// `manager.circuit-breaker-threshold` isn't set, and bypassed entirely if
+// `manager.force` is set.
+func (mgr *Manager) circuitOpen(ctx context.Context, logger *slog.Logger, module string) bool {
+	if viper.GetInt("manager.circuit-breaker-threshold") <= 0 || viper.GetBool("manager.force") {
+		return false
+	}
+
+	b, found := mgr.moduleBreakers[module]
+	if !found || b.openedAt.IsZero() {
+		return false
+	}
+
+	cooldown, err := config.GetDuration("manager.circuit-breaker-cooldown")
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to parse circuit breaker cooldown, retrying module",
+			slog.String("err", err.Error()),
+		)
+		return false
+	}
+
+	if remaining := cooldown - mgr.since(b.openedAt); remaining > 0 {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Module circuit breaker is open after repeated failures, skipping",
+			slog.Int("consecutive_failures", b.failures),
+			slog.Duration("retry_in", remaining.Round(time.Second)),
+		)
+		return true
+	}
+
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"Module circuit breaker cooldown has passed, retrying module",
+		slog.Int("consecutive_failures", b.failures),
+	)
+	return false
+}
+
+// recordModuleResult updates the module's circuit breaker with the result of
+// running it, opening the circuit if the module has failed too many times in a
+// row, or closing it on success.
+func (mgr *Manager) recordModuleResult(ctx context.Context, logger *slog.Logger, module string, runErr error) {
+	labels := prometheus.Labels{"module": module}
+
+	if runErr == nil {
+		if b, found := mgr.moduleBreakers[module]; found && !b.openedAt.IsZero() {
+			logger.InfoContext(ctx, "Module succeeded, closing circuit breaker")
+		}
+		delete(mgr.moduleBreakers, module)
+		metricManagerModuleCircuitOpen.With(labels).Set(0)
+		return
+	}
+
+	b, found := mgr.moduleBreakers[module]
+	if !found {
+		b = &moduleBreaker{}
+		mgr.moduleBreakers[module] = b
+	}
+	b.failures++
+
+	threshold := viper.GetInt("manager.circuit-breaker-threshold")
+	if threshold <= 0 || b.failures < threshold {
+		return
+	}
+
+	b.openedAt = mgr.clock.Now()
+	metricManagerModuleCircuitOpen.With(labels).Set(1)
+	logger.LogAttrs(
+		ctx,
+		slog.LevelWarn,
+		"Module failed too many times in a row, opening circuit breaker",
+		slog.Int("consecutive_failures", b.failures),
+	)
+}
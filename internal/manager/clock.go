@@ -0,0 +1,29 @@
+package manager
+
+import (
+	"time"
+)
+
+// Clock is the source of the current time for the manager. It's used for the
+// directive modification window, run IDs, metric timestamps, events, reports,
+// and the `now` template function, so that time dependent behavior can be
+// controlled (ie, frozen) by callers.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, backed by the system clock
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// SetClock sets the clock used by the manager. The manager uses the system
+// clock by default.
+func (mgr *Manager) SetClock(clock Clock) {
+	mgr.clock = clock
+}
+
+// since returns the time elapsed since t, according to the manager's clock
+func (mgr *Manager) since(t time.Time) time.Duration {
+	return mgr.clock.Now().Sub(t)
+}
@@ -46,16 +46,16 @@ func (mgr *Manager) ReloadDirectives(ctx context.Context) {
 
 // RunDirective is responsible for actually executing a directive, using the `shell`
 // package.
-func (mgr *Manager) RunDirective(ctx context.Context, ds Directive) error {
+func (mgr *Manager) RunDirective(ctx context.Context, logger *slog.Logger, ds Directive) error {
 	file, err := os.Stat(ds.String())
 	if err != nil {
 		return fmt.Errorf("Failed to stat directive script %s: %s", ds.String(), err)
 	}
 
 	// only run directive if modified within last 24h
-	if file.ModTime().After(time.Now().Add(-(time.Hour * 24))) {
-		ctx, runID := getOrSetRunID(ctx)
-		applyStart := time.Now()
+	if file.ModTime().After(mgr.clock.Now().Add(-(time.Hour * 24))) {
+		ctx, runID := mgr.getOrSetRunID(ctx)
+		applyStart := mgr.clock.Now()
 		labels := prometheus.Labels{
 			"directive": ds.String(),
 		}
@@ -66,14 +66,21 @@ func (mgr *Manager) RunDirective(ctx context.Context, ds Directive) error {
 
 		renderedScript, err := templateScript(ctx, ds.String(), allTemplateData, mgr.funcMap)
 		if err != nil {
-			return fmt.Errorf("Failed to template script: %s", err)
+			metricManagerTemplateRenderFailedTotal.With(prometheus.Labels{"module": ds.String(), "script": "directive"}).Inc()
+			return fmt.Errorf("Failed to template script: %w", err)
 		}
 
-		rc, err := shell.Run(ctx, runID, ds.String(), renderedScript, nil)
+		opts := mgr.runOptions
+		opts.Limits = getScriptLimits(ctx, logger, "")
+		opts.MaxOutputSize = getMaxOutputSize(ctx, logger)
+		rc, err := shell.RunWithOptions(ctx, runID, ds.String(), renderedScript, opts)
 		mgr.executedDirectives[ds.String()] = struct{}{} // mark directive as executed
+		if err == nil {
+			mgr.publish(ctx, Event{Type: EventScriptFinished, Directive: ds.String(), ExitCode: &rc})
+		}
 
 		// update metrics regardless of error, so do them before handling error
-		applyEnd := time.Since(applyStart)
+		applyEnd := mgr.since(applyStart)
 		metricManagerDirectiveRunSuccessTimestamp.With(labels).Set(float64(applyStart.Unix()))
 		metricManagerDirectiveRunDuration.With(labels).Set(float64(applyEnd))
 		metricManagerDirectiveRunTotal.With(labels).Inc()
@@ -87,23 +94,42 @@ func (mgr *Manager) RunDirective(ctx context.Context, ds Directive) error {
 			metricManagerDirectiveRunFailedTotal.With(labels).Inc()
 			return fmt.Errorf("Failed to apply directive, non-zero exit code returned: %d", rc)
 		}
+	} else {
+		metricManagerDirectiveSkippedTotal.With(prometheus.Labels{"directive": ds.String()}).Inc()
+		logger.LogAttrs(
+			ctx,
+			slog.LevelDebug,
+			"Directive not modified within the last 24h, skipping",
+			slog.String("path", ds.String()),
+			slog.Time("modtime", file.ModTime()),
+		)
 	}
 
 	return nil
 }
 
 // RunDirectives runs all of the directive scripts being managed by the Manager
-func (mgr *Manager) RunDirectives(ctx context.Context, logger *slog.Logger) {
-	ctx, _ = getOrSetRunID(ctx)
+// that are in the given phase (`inventory.DirectivePhasePre` or
+// `inventory.DirectivePhasePost`)
+func (mgr *Manager) RunDirectives(ctx context.Context, logger *slog.Logger, phase string) {
+	ctx, _ = mgr.getOrSetRunID(ctx)
+	logger = logger.With(slog.String("phase", phase))
 
-	if len(mgr.directives) <= 0 {
+	var directives []Directive
+	for _, d := range mgr.directives {
+		if d.d.Phase == phase {
+			directives = append(directives, d)
+		}
+	}
+
+	if len(directives) <= 0 {
 		logger.InfoContext(ctx, "No Directives to run")
 		return
 	}
 
 	logger.InfoContext(ctx, "Directive run started")
 	defer logger.InfoContext(ctx, "Directive run finished")
-	for _, d := range mgr.directives {
+	for _, d := range directives {
 		dLogger := logger.With(
 			slog.Group(
 				"directive",
@@ -111,16 +137,33 @@ func (mgr *Manager) RunDirectives(ctx context.Context, logger *slog.Logger) {
 			),
 		)
 
+		if ctx.Err() != nil {
+			// run was canceled (ie, run timeout exceeded, or aborted
+			// after a fatal template error)
+			mgr.publish(ctx, Event{Type: EventDirectiveFinished, Directive: d.String(), Error: context.Cause(ctx).Error()})
+			dLogger.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"Run canceled, skipping directive",
+				slog.String("err", context.Cause(ctx).Error()),
+			)
+			continue
+		}
+
 		dLogger.InfoContext(ctx, "Directive started")
 		defer dLogger.InfoContext(ctx, "Directive finished")
+		mgr.publish(ctx, Event{Type: EventDirectiveStarted, Directive: d.String()})
 
-		if err := mgr.RunDirective(ctx, d); err != nil {
+		err := mgr.RunDirective(ctx, dLogger, d)
+		mgr.publish(ctx, Event{Type: EventDirectiveFinished, Directive: d.String(), Error: errString(err)})
+		if err != nil {
 			dLogger.LogAttrs(
 				ctx,
 				slog.LevelError,
 				"Directive failed",
 				slog.String("err", err.Error()),
 			)
+			mgr.abortOnTemplateError(ctx, dLogger, err)
 		}
 	}
 }
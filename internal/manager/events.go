@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType is the type of a converge progress event published by the manager.
+type EventType string
+
+const (
+	EventRunStarted        EventType = "run_started"
+	EventRunFinished       EventType = "run_finished"
+	EventDirectiveStarted  EventType = "directive_started"
+	EventDirectiveFinished EventType = "directive_finished"
+	EventModuleStarted     EventType = "module_started"
+	EventModuleFinished    EventType = "module_finished"
+	EventScriptFinished    EventType = "script_finished"
+)
+
+// eventSubscriberBufferSize is the number of events buffered for each
+// subscriber. Events are dropped for subscribers that fall further behind, so
+// that a slow consumer can never block a converge.
+const eventSubscriberBufferSize = 64
+
+// Event is a converge progress event published by the manager to subscribers
+// as directives, modules, and scripts are run.
+type Event struct {
+	Type      EventType `json:"type"`
+	Time      time.Time `json:"time"`
+	Manager   string    `json:"manager"`
+	RunID     string    `json:"run_id,omitempty"`
+	Directive string    `json:"directive,omitempty"`
+	Module    string    `json:"module,omitempty"`
+	Script    string    `json:"script,omitempty"`
+	ExitCode  *uint8    `json:"exit_code,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel that receives the manager's converge progress
+// events, along with a function that must be called to unsubscribe once the
+// caller is done receiving events.
+func (mgr *Manager) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventSubscriberBufferSize)
+
+	mgr.events.mu.Lock()
+	mgr.events.subscribers[ch] = struct{}{}
+	mgr.events.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			mgr.events.mu.Lock()
+			delete(mgr.events.subscribers, ch)
+			mgr.events.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// publish sends the event to all current subscribers, filling in the common
+// fields from the manager and context.
+func (mgr *Manager) publish(ctx context.Context, e Event) {
+	e.Time = mgr.clock.Now()
+	e.Manager = mgr.String()
+	if e.RunID == "" {
+		e.RunID = getRunMetadata(ctx, "").RunID
+	}
+
+	if mgr.report != nil {
+		mgr.report.record(e)
+	}
+
+	mgr.events.mu.Lock()
+	defer mgr.events.mu.Unlock()
+
+	for ch := range mgr.events.subscribers {
+		select {
+		case ch <- e:
+		default:
+			metricManagerEventsDroppedTotal.Inc()
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
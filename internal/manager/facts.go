@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// createFactsFile creates the empty file that a module's apply script may
+// write facts to, provided to the script as `MANGO_FACTS`.
+func createFactsFile() (string, error) {
+	f, err := os.CreateTemp(viper.GetString("mango.temp-dir"), "facts-")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create module facts file: %v", err)
+	}
+	defer f.Close()
+
+	return f.Name(), nil
+}
+
+// readFactsFile parses the facts written by a module's apply script. Facts are
+// `key=value` lines, where the key must be a valid variable name. Blank lines
+// and lines starting with `#` are ignored, and invalid lines are logged and
+// skipped. If a key is written multiple times, the last value wins.
+func readFactsFile(ctx context.Context, logger *slog.Logger, path string) (VariableMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open module facts file: %v", err)
+	}
+	defer f.Close()
+
+	facts := make(VariableMap)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, val, found := strings.Cut(line, "=")
+		if !found || !syntax.ValidName(key) {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"Invalid module fact, skipping",
+				slog.Int("line", lineNum),
+			)
+			continue
+		}
+
+		facts[key] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read module facts file: %v", err)
+	}
+
+	return facts, nil
+}
+
+// recordModuleFacts stores the facts from the module's latest successful apply,
+// replacing any previous facts from the module. Facts are kept until the
+// module is run again, so that modules skipped as unchanged continue to
+// provide their facts to later modules.
+func (mgr *Manager) recordModuleFacts(id string, facts VariableMap) {
+	if len(facts) == 0 {
+		delete(mgr.moduleFacts, id)
+		return
+	}
+
+	mgr.moduleFacts[id] = facts
+}
+
+// mergeModuleFacts makes the module's facts, if any, available to the modules
+// run after it in the current run. Facts from modules later in the run order
+// take precedence.
+func (mgr *Manager) mergeModuleFacts(id string) {
+	if facts, found := mgr.moduleFacts[id]; found {
+		maps.Copy(mgr.runFacts, facts)
+	}
+}
@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// moduleFingerprint returns a sha256 checksum representing the current state
+// of everything that can influence a module's run: the contents of the
+// module's scripts/files and templates, the host level templates, the
+// variables/env the module is run with, and the module's settings (ie, the
+// platforms it supports).
+func (mgr *Manager) moduleFingerprint(mod Module) (string, error) {
+	h := sha256.New()
+
+	paths := []string{mod.m.Apply, mod.m.Test, mod.m.Variables, mod.m.Requires, mod.m.Limits, mod.m.WorkDir, mod.m.Stdin, mod.m.Become}
+	paths = append(paths, mod.m.VariablesDir...)
+	paths = append(paths, mod.m.TemplateFiles...)
+	paths = append(paths, mgr.hostTemplates...)
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		if err := hashFile(h, path); err != nil {
+			return "", err
+		}
+	}
+
+	// sort vars so that map iteration order during merging doesn't change
+	// the fingerprint
+	vars := slices.Concat(mgr.hostVariables, mod.Variables, mod.m.Env, []string{mod.stateVar()})
+	slices.Sort(vars)
+	for _, v := range vars {
+		fmt.Fprintf(h, "%s\n", v)
+	}
+
+	// settings are hashed in a fixed order, and supported platforms are
+	// sorted as their order doesn't matter
+	fmt.Fprintf(h, "supported-os=%s\n", strings.Join(slices.Sorted(slices.Values(mod.m.SupportedOS)), ","))
+	fmt.Fprintf(h, "supported-arch=%s\n", strings.Join(slices.Sorted(slices.Values(mod.m.SupportedArch)), ","))
+	if mod.m.SkipApplyOnTestSuccess != nil {
+		fmt.Fprintf(h, "skip-apply-on-test-success=%t\n", *mod.m.SkipApplyOnTestSuccess)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open file for fingerprinting: %v", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(w, "%s\n", path)
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("Failed to read file for fingerprinting: %v", err)
+	}
+
+	return nil
+}
+
+// fullConverge returns true if every module should be run on every run, which
+// is the default. Only running modules that have changed since their last
+// successful run is opt-in with `manager.incremental-converge`, as it doesn't
+// correct local drift on the host for unchanged modules.
+func fullConverge() bool {
+	return !viper.GetBool("manager.incremental-converge") || viper.GetBool("manager.force-full-converge")
+}
+
+// getModulesToRun compares the current fingerprint of each module against the
+// fingerprint recorded on that module's last successful run. It returns a map
+// of the IDs of modules that need to be run (changed modules, as well as
+// anything that depends on a changed module) to their current fingerprints.
+func (mgr *Manager) getModulesToRun(ctx context.Context, logger *slog.Logger, order []string) map[string]string {
+	fullConverge := fullConverge()
+	toRun := make(map[string]string)
+
+	for _, v := range order {
+		mod, err := mgr.modules.Vertex(v)
+		if err != nil {
+			// can't fingerprint it, let the run loop handle the error
+			toRun[v] = ""
+			continue
+		}
+
+		fp, err := mgr.moduleFingerprint(mod)
+		if err != nil {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"Failed to fingerprint module, it will be run",
+				slog.String("err", err.Error()),
+				slog.String("module", v),
+			)
+		}
+
+		if fullConverge || fp == "" || mgr.moduleFingerprints[v] != fp {
+			toRun[v] = fp
+		}
+	}
+
+	if fullConverge {
+		return toRun
+	}
+
+	// walk the DAG to make sure that anything downstream of a changed
+	// module is also run
+	adjacency, err := mgr.modules.AdjacencyMap()
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to get adjacency map for directed acyclic graph, running all modules",
+			slog.String("err", err.Error()),
+		)
+
+		for _, v := range order {
+			if _, found := toRun[v]; !found {
+				toRun[v], _ = mgr.moduleFingerprintByID(v)
+			}
+		}
+
+		return toRun
+	}
+
+	var queue []string
+	for v := range toRun {
+		queue = append(queue, v)
+	}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		for dependent := range adjacency[v] {
+			if _, found := toRun[dependent]; found {
+				continue
+			}
+
+			toRun[dependent], _ = mgr.moduleFingerprintByID(dependent)
+			queue = append(queue, dependent)
+		}
+	}
+
+	return toRun
+}
+
+func (mgr *Manager) moduleFingerprintByID(id string) (string, error) {
+	mod, err := mgr.modules.Vertex(id)
+	if err != nil {
+		return "", err
+	}
+
+	return mgr.moduleFingerprint(mod)
+}
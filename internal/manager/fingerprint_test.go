@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tjhop/mango/internal/inventory"
+)
+
+func TestModuleFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	apply := writeFile("apply", "true")
+	limits := writeFile("limits", "memory=512MiB")
+	otherLimits := writeFile("other-limits", "memory=1GiB")
+	skip := true
+
+	base := inventory.Module{ID: "test", Apply: apply, Limits: limits, SupportedOS: []string{"debian", "rhel"}, SupportedArch: []string{"amd64"}}
+	tests := []struct {
+		name    string
+		modify  func(m *inventory.Module)
+		changed bool
+	}{
+		{
+			name:   "unchanged",
+			modify: func(m *inventory.Module) {},
+		},
+		{
+			name:   "supported os order",
+			modify: func(m *inventory.Module) { m.SupportedOS = []string{"rhel", "debian"} },
+		},
+		{
+			name:    "limits",
+			modify:  func(m *inventory.Module) { m.Limits = otherLimits },
+			changed: true,
+		},
+		{
+			name:    "supported os",
+			modify:  func(m *inventory.Module) { m.SupportedOS = []string{"debian"} },
+			changed: true,
+		},
+		{
+			name:    "supported arch",
+			modify:  func(m *inventory.Module) { m.SupportedArch = nil },
+			changed: true,
+		},
+		{
+			name:    "skip apply on test success",
+			modify:  func(m *inventory.Module) { m.SkipApplyOnTestSuccess = &skip },
+			changed: true,
+		},
+	}
+
+	mgr := NewManager("test")
+	want, err := mgr.moduleFingerprint(Module{m: base})
+	if err != nil {
+		t.Fatalf("moduleFingerprint() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base
+			tt.modify(&m)
+
+			got, err := mgr.moduleFingerprint(Module{m: m})
+			if err != nil {
+				t.Fatalf("moduleFingerprint() error = %v", err)
+			}
+			if changed := got != want; changed != tt.changed {
+				t.Errorf("moduleFingerprint() changed = %t, want %t", changed, tt.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/shell"
+)
+
+// runFailureHook runs the script configured by `manager.on-failure`, if any,
+// after a module fails. Details of the failure are provided to the hook as
+// environment variables:
+// - MANGO_FAILED_MODULE: the ID of the module that failed
+// - MANGO_EXIT_CODE: the exit code of the module's apply script, if it ran
+// - MANGO_ERROR: the error the module failed with
+// - MANGO_RUN_ID: the ID of the run the module failed in
+// - MANGO_HOSTNAME: the hostname the manager is running as
+//
+// The hook is only ever run for module failures, so a failing hook is logged
+// and counted, but never triggers the hook again.
+func (mgr *Manager) runFailureHook(ctx context.Context, logger *slog.Logger, module string, runErr error) {
+	path := viper.GetString("manager.on-failure")
+	if path == "" {
+		return
+	}
+
+	ctx, runID := mgr.getOrSetRunID(ctx)
+	hLogger := logger.With(slog.String("hook", path))
+
+	if err := mgr.runFailureHookScript(ctx, hLogger, runID, path, module, runErr); err != nil {
+		metricManagerFailureHookFailedTotal.Inc()
+		hLogger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failure hook failed",
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	hLogger.DebugContext(ctx, "Failure hook finished")
+}
+
+func (mgr *Manager) runFailureHookScript(ctx context.Context, logger *slog.Logger, runID ulid.ULID, path, module string, runErr error) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read failure hook script: %v", err)
+	}
+
+	exitCode := ""
+	var ece exitCodeError
+	if errors.As(runErr, &ece) {
+		exitCode = strconv.Itoa(int(ece.code))
+	}
+
+	opts := mgr.runOptions
+	opts.MaxOutputSize = getMaxOutputSize(ctx, logger)
+	opts.Vars = []string{
+		"MANGO_FAILED_MODULE=" + module,
+		"MANGO_EXIT_CODE=" + exitCode,
+		"MANGO_ERROR=" + errString(runErr),
+		"MANGO_RUN_ID=" + runID.String(),
+		"MANGO_HOSTNAME=" + mgr.String(),
+	}
+
+	rc, err := shell.RunWithOptions(ctx, runID, path, string(content), opts)
+	if err != nil {
+		return fmt.Errorf("Failed to run failure hook script: %v", err)
+	}
+
+	if rc != 0 {
+		return fmt.Errorf("Failed to run failure hook script, non-zero exit code returned: %d", rc)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/tjhop/mango/internal/inventory"
+)
+
+// reloadHostScripts reloads the host's own apply/test scripts from the
+// inventory. Host scripts are wrapped in a Module so that they can be run
+// (and tracked in metrics) the same way as any other module.
+func (mgr *Manager) reloadHostScripts(inv inventory.Store) {
+	mgr.hostScripts = nil
+
+	h, found := inv.GetHost(inv.GetHostname())
+	if !found || (h.Apply == "" && h.Test == "") {
+		return
+	}
+
+	mgr.hostScripts = &Module{
+		m: inventory.Module{
+			ID:    filepath.Join("hosts", h.String()),
+			Path:  filepath.Join(inv.GetInventoryPath(), "hosts", h.String()),
+			Apply: h.Apply,
+			Test:  h.Test,
+		},
+	}
+}
+
+// RunHostScripts runs the host's own apply/test scripts, if any are defined
+// for the host in the inventory. Host scripts are run after all modules.
+func (mgr *Manager) RunHostScripts(ctx context.Context, logger *slog.Logger) {
+	ctx, _ = mgr.getOrSetRunID(ctx)
+
+	if mgr.hostScripts == nil {
+		logger.DebugContext(ctx, "No host scripts to run")
+		return
+	}
+
+	mod := *mgr.hostScripts
+	id := mod.String()
+	hLogger := logger.With(
+		slog.Group(
+			"host",
+			slog.String("id", id),
+		),
+	)
+
+	fingerprint, err := mgr.moduleFingerprint(mod)
+	if err != nil {
+		hLogger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Failed to fingerprint host scripts, they will be run",
+			slog.String("err", err.Error()),
+		)
+	}
+
+	if !fullConverge() && fingerprint != "" && mgr.moduleFingerprints[id] == fingerprint {
+		hLogger.DebugContext(ctx, "Host scripts unchanged since last successful run, skipping")
+		return
+	}
+
+	if ctx.Err() != nil {
+		// run was canceled (ie, run timeout exceeded, or aborted after a
+		// fatal template error)
+		mgr.publish(ctx, Event{Type: EventModuleFinished, Module: id, Error: context.Cause(ctx).Error()})
+		hLogger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Run canceled, skipping host scripts",
+			slog.String("err", context.Cause(ctx).Error()),
+		)
+		return
+	}
+
+	hLogger.InfoContext(ctx, "Host scripts started")
+	defer hLogger.InfoContext(ctx, "Host scripts finished")
+	mgr.publish(ctx, Event{Type: EventModuleStarted, Module: id})
+
+	err = mgr.RunModule(ctx, hLogger, mod)
+	mgr.publish(ctx, Event{Type: EventModuleFinished, Module: id, Error: errString(err)})
+	if err != nil {
+		delete(mgr.moduleFingerprints, id)
+		hLogger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Host scripts failed",
+			slog.String("err", err.Error()),
+		)
+		mgr.runFailureHook(ctx, hLogger, id, err)
+		return
+	}
+
+	if fingerprint != "" {
+		mgr.moduleFingerprints[id] = fingerprint
+	}
+}
@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/tjhop/mango/internal/config"
+	"github.com/tjhop/mango/internal/shell"
+)
+
+// getScriptLimits returns the resource limits for a script, using the globally
+// configured limits overridden by any limits set in the provided module limits
+// file (if any). Failures to parse limits are logged, and the affected limits
+// are left unset.
+func getScriptLimits(ctx context.Context, logger *slog.Logger, moduleLimitsPath string) shell.Limits {
+	var limits shell.Limits
+	cpu, cpuErr := config.GetDuration("manager.script-cpu-limit")
+	memory, memoryErr := config.GetSize("manager.script-memory-limit")
+	if memoryErr == nil {
+		memoryErr = shell.ValidateMemoryLimit(memory)
+		if memoryErr != nil {
+			memory = 0
+		}
+	}
+	if err := errors.Join(cpuErr, memoryErr); err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to parse global script resource limits",
+			slog.String("err", err.Error()),
+		)
+	}
+	limits.CPUTime, limits.Memory = cpu, memory
+
+	if moduleLimitsPath == "" {
+		return limits
+	}
+
+	modLimits, err := shell.ParseLimitsFile(moduleLimitsPath)
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to parse module resource limits",
+			slog.String("err", err.Error()),
+			slog.String("path", moduleLimitsPath),
+		)
+		return limits
+	}
+
+	return limits.Merge(modLimits)
+}
+
+// getMaxOutputSize returns the globally configured maximum size of each of a
+// script's stdout/stderr log files. Failures to parse the size are logged, and
+// output is left unlimited.
+func getMaxOutputSize(ctx context.Context, logger *slog.Logger) uint64 {
+	size, err := config.GetSize("manager.max-output-size")
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to parse max script output size, output will be unlimited",
+			slog.String("err", err.Error()),
+		)
+	}
+
+	return size
+}
@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/config"
+	"github.com/tjhop/mango/internal/inventory"
+)
+
+// Valid values for `manager.run-overlap`, controlling what happens when a run
+// is requested while another run is in progress.
+const (
+	// RunOverlapAbort drops the requested run
+	RunOverlapAbort = "abort"
+	// RunOverlapWait waits for the run in progress to finish, for up to
+	// `manager.run-overlap-timeout`, before starting the requested run
+	RunOverlapWait = "wait"
+	// RunOverlapQueueOne queues the requested run to start as soon as the
+	// run in progress finishes. At most one run is queued, and further
+	// requests are coalesced into it.
+	RunOverlapQueueOne = "queue-one"
+)
+
+// pendingRun is a run queued with `RunOverlapQueueOne`. The inventory to
+// reload from, if any, is kept so that the queued run only reloads once it
+// holds the run lock.
+type pendingRun struct {
+	ctx    context.Context
+	logger *slog.Logger
+	inv    inventory.Store
+}
+
+// lockRun acquires the manager's run lock, according to the configured
+// `manager.run-overlap` policy. It returns false if the run lock couldn't be
+// acquired and the requested run should be dropped (or has been queued). Only
+// queueable runs are queued, as synchronous callers need the run's result.
+func (mgr *Manager) lockRun(ctx context.Context, logger *slog.Logger, inv inventory.Store, queueable bool) bool {
+	select {
+	case mgr.runLock <- struct{}{}:
+		return true
+	default:
+	}
+
+	switch policy := strings.ToLower(strings.TrimSpace(viper.GetString("manager.run-overlap"))); {
+	case policy == RunOverlapQueueOne && queueable:
+		return mgr.queueRun(ctx, logger, inv)
+	case policy != RunOverlapWait:
+		logger.WarnContext(ctx, "Manager run already in progress, aborting")
+		return false
+	}
+
+	waitCtx := ctx
+	timeout, _ := config.GetDuration("manager.run-overlap-timeout") // validated at startup
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"Manager run already in progress, waiting for it to finish",
+		slog.String("timeout", timeout.String()),
+	)
+
+	select {
+	case mgr.runLock <- struct{}{}:
+		return true
+	case <-waitCtx.Done():
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Failed to wait for manager run in progress, aborting",
+			slog.String("err", context.Cause(waitCtx).Error()),
+		)
+		return false
+	}
+}
+
+// queueRun queues the requested run to start once the run in progress
+// finishes, replacing any run already queued. It returns true if the run in
+// progress finished in the meantime and the run lock was acquired instead.
+func (mgr *Manager) queueRun(ctx context.Context, logger *slog.Logger, inv inventory.Store) bool {
+	mgr.pendingLock.Lock()
+	defer mgr.pendingLock.Unlock()
+
+	// the run lock is only released while holding the pending lock, so
+	// the run in progress either already released it, or will pick up
+	// the queued run when it does
+	select {
+	case mgr.runLock <- struct{}{}:
+		return true
+	default:
+	}
+
+	if mgr.pending != nil {
+		logger.InfoContext(ctx, "Manager run already in progress and another run is queued, coalescing into queued run")
+	} else {
+		logger.InfoContext(ctx, "Manager run already in progress, queueing run to start once it finishes")
+	}
+	mgr.pending = &pendingRun{ctx: ctx, logger: logger, inv: inv}
+	metricManagerRunsQueued.With(prometheus.Labels{"manager": mgr.String()}).Set(1)
+
+	return false
+}
+
+// unlockRun releases the manager's run lock, and starts the queued run, if
+// any.
+func (mgr *Manager) unlockRun() {
+	mgr.pendingLock.Lock()
+	next := mgr.pending
+	mgr.pending = nil
+	<-mgr.runLock
+	mgr.pendingLock.Unlock()
+
+	if next != nil {
+		metricManagerRunsQueued.With(prometheus.Labels{"manager": mgr.String()}).Set(0)
+		go mgr.runAll(next.ctx, next.logger, next.inv, true)
+	}
+}
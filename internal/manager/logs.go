@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/config"
+)
+
+// compressRunLogs gzip compresses the script logs (stdout, stderr, rendered
+// scripts, etc) of completed runs older than `manager.compress-run-logs-age`,
+// if `manager.compress-run-logs` is enabled. Compressed files are suffixed
+// with `.gz` and replace the originals, so that logs are preserved for
+// auditing while taking up less space. The current run's logs are never
+// compressed.
+func (mgr *Manager) compressRunLogs(ctx context.Context, logger *slog.Logger, currentRunID ulid.ULID) {
+	if !viper.GetBool("manager.compress-run-logs") {
+		return
+	}
+
+	age, err := config.GetDuration("manager.compress-run-logs-age")
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to parse run log compression age, skipping compression",
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	runsDir := filepath.Join(viper.GetString("mango.log-dir"), "manager/run")
+	runDirs, err := os.ReadDir(runsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"Failed to read run log directory",
+				slog.String("err", err.Error()),
+				slog.String("path", runsDir),
+			)
+		}
+		return
+	}
+
+	cutoff := mgr.clock.Now().Add(-age)
+	for _, runDir := range runDirs {
+		// run log directories are named after the run's ULID, which
+		// encodes when the run started
+		id, err := ulid.Parse(runDir.Name())
+		if !runDir.IsDir() || err != nil || id == currentRunID || ulid.Time(id.Time()).After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(runsDir, runDir.Name())
+		if err := compressDir(path); err != nil {
+			metricManagerRunLogsCompressFailedTotal.Inc()
+			logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"Failed to compress run logs",
+				slog.String("err", err.Error()),
+				slog.String("path", path),
+			)
+		}
+	}
+}
+
+// compressDir gzip compresses all regular files in the directory tree that
+// aren't already compressed.
+func compressDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() || strings.HasSuffix(path, ".gz") {
+			return nil
+		}
+
+		return compressFile(path)
+	})
+}
+
+// compressFile gzip compresses the file to `path.gz`, and removes the
+// original.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open log file: %v", err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to stat log file: %v", err)
+	}
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("Failed to create compressed log file: %v", err)
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(path)
+	gz.ModTime = info.ModTime()
+	if _, err := io.Copy(gz, in); err != nil {
+		os.Remove(path + ".gz")
+		return fmt.Errorf("Failed to compress log file: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return fmt.Errorf("Failed to compress log file: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return fmt.Errorf("Failed to write compressed log file: %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("Failed to remove uncompressed log file: %v", err)
+	}
+
+	return nil
+}
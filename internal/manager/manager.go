@@ -2,17 +2,25 @@ package manager
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/dominikbraun/graph"
 	"github.com/dustin/go-humanize"
 	"github.com/oklog/ulid/v2"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
 	"mvdan.cc/sh/v3/syntax"
 
+	"github.com/tjhop/mango/internal/config"
 	"github.com/tjhop/mango/internal/inventory"
 	"github.com/tjhop/mango/internal/shell"
 	"github.com/tjhop/mango/pkg/utils"
@@ -31,6 +39,9 @@ var (
 	contextKeyManagerName   = contextKey("manager_name")
 	contextKeyInventoryPath = contextKey("inventory_path")
 	contextKeyHostname      = contextKey("hostname")
+
+	// context key for the subset of modules to run, see `ConvergeModules()`
+	contextKeyModules = contextKey("modules")
 )
 
 // Manager contains fields related to track and execute runnable modules and statistics.
@@ -39,56 +50,116 @@ type Manager struct {
 	inv                inventory.Store // TODO: move this interface to be defined consumer-side in manager vs in inventory
 	modules            graph.Graph[string, Module]
 	directives         []Directive
-	executedDirectives map[string]struct{} // stores the ID of the directive as key
+	executedDirectives map[string]struct{}       // stores the ID of the directive as key
+	moduleFingerprints map[string]string         // stores the fingerprint of the module's last successful run, keyed by module ID
+	moduleBreakers     map[string]*moduleBreaker // stores the circuit breaker state of failing modules, keyed by module ID
+	moduleFacts        map[string]VariableMap    // stores the facts from each module's last successful apply, keyed by module ID
+	runFacts           VariableMap               // facts accumulated from the modules run so far in the run in progress
+	store              *runStore                 // key-value store shared between modules, keyed by the module that set each value
 	hostVariables      VariableSlice
 	hostTemplates      []string
-	runLock            sync.Mutex
+	hostScripts        *Module       // host's own apply/test scripts, if defined
+	runLock            chan struct{} // single slot semaphore held for the duration of a run, see `lockRun()`
+	pendingLock        sync.Mutex
+	pending            *pendingRun // run queued to start once the run in progress finishes, if any
 	funcMap            template.FuncMap
 	tmplData           templateData
+	hostnames          hostnameMetadata // system's FQDN and short hostname, resolved on reload
+	events             *eventBus
+	report             *RunReport              // report for the run in progress, if any
+	notEnrolled        bool                    // whether the last reload skipped the run because the host isn't enrolled
+	runOptions         shell.RunOptions        // options applied to every script run, see `SetRunOptions()`
+	clock              Clock                   // source of the current time, see `SetClock()`
+	abortRun           context.CancelCauseFunc // aborts the run in progress, if any
+	reloadTemplateErr  error                   // template error from the last reload, if any
 }
 
 func (mgr *Manager) String() string { return mgr.id }
 
 // NewManager returns a new Manager struct instantiated with the given ID
 func NewManager(id string) *Manager {
-	funcs := template.FuncMap{
+	mgr := &Manager{
+		id:                 id,
+		modules:            graph.New(moduleHash, graph.Directed(), graph.Acyclic()),
+		executedDirectives: make(map[string]struct{}),
+		moduleFingerprints: make(map[string]string),
+		moduleBreakers:     make(map[string]*moduleBreaker),
+		moduleFacts:        make(map[string]VariableMap),
+		store:              newRunStore(),
+		events:             newEventBus(),
+		clock:              realClock{},
+		runLock:            make(chan struct{}, 1),
+	}
+
+	// os-release and mount helpers read the manager's current system metadata,
+	// which is refreshed on each reload
+	mgr.funcMap = template.FuncMap{
 		"isIPv4":         utils.IsIPv4,
 		"isIPv6":         utils.IsIPv6,
 		"humanizeBytes":  humanize.Bytes,
 		"humanizeIBytes": humanize.IBytes,
+		"osID":           func() string { return mgr.tmplData.OS.ID() },
+		"osVersionID":    func() string { return mgr.tmplData.OS.VersionID() },
+		"osLike":         func() []string { return mgr.tmplData.OS.Like() },
+		"isDistro":       func(name string) bool { return mgr.tmplData.OS.IsDistro(name) },
+		"distroFamily":   func() string { return mgr.tmplData.OS.Family() },
+		"mountFor":       func(path string) *mount { return mgr.tmplData.Storage.MountFor(path) },
+		"fsTypeFor":      func(path string) string { return mgr.tmplData.Storage.FSTypeFor(path) },
+		"now":            func() time.Time { return mgr.clock.Now() },
+		// mangoGet returns the value of the key in the run's
+		// key-value store, or an empty string if unset
+		"mangoGet": func(key string) string {
+			val, _ := mgr.store.Get(key)
+			return val
+		},
 	}
 
-	return &Manager{
-		id:      id,
-		funcMap: funcs,
-		modules: graph.New(moduleHash, graph.Directed(), graph.Acyclic()),
-	}
+	return mgr
 }
 
-func getOrSetRunID(ctx context.Context) (context.Context, ulid.ULID) {
+// SetRunOptions sets the base options for every script run by the manager,
+// ie to stream script output to the terminal for interactive runs. Options
+// specific to the script being run (variables, limits, etc) are populated by
+// the manager on top of these. The mango daemon only writes script output to
+// log files.
+func (mgr *Manager) SetRunOptions(opts shell.RunOptions) {
+	mgr.runOptions = opts
+}
+
+func (mgr *Manager) getOrSetRunID(ctx context.Context) (context.Context, ulid.ULID) {
 	id := ctx.Value(contextKeyRunID)
 
 	if id == nil || id.(ulid.ULID).String() == "" {
-		id = ulid.Make()
+		id = ulid.MustNew(ulid.Timestamp(mgr.clock.Now()), ulid.DefaultEntropy())
 		ctx = context.WithValue(ctx, contextKeyRunID, id)
 	}
 
 	return ctx, id.(ulid.ULID)
 }
 
-// ReloadAndRunAll is a wrapper function to reload from the specified
-// inventory, populate some run specific context, and initiate a run of all
-// managed modules
-func (mgr *Manager) ReloadAndRunAll(ctx context.Context, logger *slog.Logger, inv inventory.Store) {
-	// add context data relevant to this run, for use with templating and things
-	ctx, runID := getOrSetRunID(ctx)
+// withRunContext populates the context with the run specific metadata that
+// is used when templating (run ID, enrollment status, etc).
+func (mgr *Manager) withRunContext(ctx context.Context, inv inventory.Store) (context.Context, ulid.ULID) {
+	ctx, runID := mgr.getOrSetRunID(ctx)
 
-	enrolled := inv.IsEnrolled()
-	ctx = context.WithValue(ctx, contextKeyEnrolled, enrolled)
+	ctx = context.WithValue(ctx, contextKeyEnrolled, inv.IsEnrolled())
 	ctx = context.WithValue(ctx, contextKeyManagerName, mgr.String())
 	ctx = context.WithValue(ctx, contextKeyInventoryPath, inv.GetInventoryPath())
 	ctx = context.WithValue(ctx, contextKeyHostname, inv.GetHostname())
 
+	return ctx, runID
+}
+
+// ReloadAndRunAll is a wrapper function to reload from the specified
+// inventory, populate some run specific context, and initiate a run of all
+// managed modules. If the host is not enrolled in the inventory, nothing is
+// reloaded or run unless `manager.run-when-not-enrolled` is set. Nothing is
+// reloaded or run while the host-local `manager.disable-file` exists, either.
+func (mgr *Manager) ReloadAndRunAll(ctx context.Context, logger *slog.Logger, inv inventory.Store) {
+	// add context data relevant to this run, for use with templating and things
+	ctx, runID := mgr.withRunContext(ctx, inv)
+	enrolled := ctx.Value(contextKeyEnrolled).(bool)
+
 	mLogger := logger.With(
 		slog.Group(
 			"manager",
@@ -99,14 +170,105 @@ func (mgr *Manager) ReloadAndRunAll(ctx context.Context, logger *slog.Logger, in
 		),
 	)
 
-	mgr.Reload(ctx, mLogger, inv)
-	mgr.RunAll(ctx, mLogger)
+	if disableFile := viper.GetString("manager.disable-file"); disableFile != "" {
+		_, err := os.Stat(disableFile)
+		if err == nil {
+			metricManagerLocallyDisabled.With(prometheus.Labels{"manager": mgr.String()}).Set(1)
+			mLogger.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"Host is locally disabled, skipping run until the disable file is removed",
+				slog.String("path", disableFile),
+			)
+			return
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			mLogger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"Failed to check for local disable file, running anyway",
+				slog.String("err", err.Error()),
+				slog.String("path", disableFile),
+			)
+		}
+	}
+	metricManagerLocallyDisabled.With(prometheus.Labels{"manager": mgr.String()}).Set(0)
+
+	notEnrolled := !enrolled && !viper.GetBool("manager.run-when-not-enrolled")
+	if notEnrolled {
+		metricManagerNotEnrolled.With(prometheus.Labels{"manager": mgr.String()}).Set(1)
+	} else {
+		metricManagerNotEnrolled.With(prometheus.Labels{"manager": mgr.String()}).Set(0)
+	}
+
+	// only log about skipping unenrolled hosts once, rather than every
+	// reload, to cut down on noise for fleets with many unenrolled hosts
+	if notEnrolled {
+		if !mgr.notEnrolled {
+			mLogger.InfoContext(ctx, "Host is not enrolled in inventory, skipping runs until it is")
+		}
+		mgr.notEnrolled = true
+		return
+	}
+	mgr.notEnrolled = false
+
+	go mgr.runAll(ctx, mLogger, inv, true)
+}
+
+// Converge reloads from the specified inventory and synchronously runs all
+// managed directives/modules, returning the report of the run once it
+// finishes. Unlike ReloadAndRunAll, an error is returned if the host is not
+// enrolled in the inventory (and `manager.run-when-not-enrolled` isn't set),
+// or if a run is already in progress.
+func (mgr *Manager) Converge(ctx context.Context, logger *slog.Logger, inv inventory.Store) (*RunReport, error) {
+	ctx, runID := mgr.withRunContext(ctx, inv)
+
+	if !inv.IsEnrolled() && !viper.GetBool("manager.run-when-not-enrolled") {
+		return nil, fmt.Errorf("Host %s is not enrolled in inventory", inv.GetHostname())
+	}
+
+	mLogger := logger.With(
+		slog.Group(
+			"manager",
+			slog.String("inventory", inv.GetInventoryPath()),
+			slog.String("hostname", inv.GetHostname()),
+			slog.Bool(string(contextKeyEnrolled), inv.IsEnrolled()),
+			slog.String(string(contextKeyRunID), runID.String()),
+		),
+	)
+
+	report := mgr.runAll(ctx, mLogger, inv, false)
+	if report == nil {
+		return nil, fmt.Errorf("Manager run already in progress")
+	}
+
+	return report, nil
+}
+
+// ConvergeModules is like Converge, but only runs the provided modules (in
+// dependency order), rather than everything managed for the host.
+// Requirements on modules outside of the provided modules are ignored, and
+// directives and the host's own scripts are not run.
+func (mgr *Manager) ConvergeModules(ctx context.Context, logger *slog.Logger, inv inventory.Store, modules []string) (*RunReport, error) {
+	subset := make(map[string]struct{}, len(modules))
+	for _, mod := range modules {
+		subset[mod] = struct{}{}
+	}
+
+	return mgr.Converge(context.WithValue(ctx, contextKeyModules, subset), logger, inv)
+}
+
+// getModuleSubset returns the subset of modules to run from the context, and
+// whether or not the run is limited to a subset of modules.
+func getModuleSubset(ctx context.Context) (map[string]struct{}, bool) {
+	subset, ok := ctx.Value(contextKeyModules).(map[string]struct{})
+	return subset, ok
 }
 
 // Reload accepts a struct that fulfills the inventory.Store interface and
 // reloads the hosts modules/directives from the inventory
 func (mgr *Manager) Reload(ctx context.Context, logger *slog.Logger, inv inventory.Store) {
-	ctx, _ = getOrSetRunID(ctx)
+	ctx, _ = mgr.getOrSetRunID(ctx)
 
 	// reload manager's knowledge of system info
 	mgr.tmplData.OS = getOSMetadata(ctx, logger)
@@ -114,9 +276,11 @@ func (mgr *Manager) Reload(ctx context.Context, logger *slog.Logger, inv invento
 	mgr.tmplData.CPU = getCPUMetadata(ctx, logger)
 	mgr.tmplData.Memory = getMemoryMetadata(ctx, logger)
 	mgr.tmplData.Storage = getStorageMetadata(ctx, logger)
+	mgr.hostnames = getHostnameMetadata(ctx, logger)
 
 	// reload manager's copy of inventory from provided inventory
 	logger.InfoContext(ctx, "Reloading items from inventory")
+	mgr.reloadTemplateErr = nil
 
 	mgr.inv = inv
 	// reload modules
@@ -136,83 +300,255 @@ func (mgr *Manager) Reload(ctx context.Context, logger *slog.Logger, inv invento
 	}
 
 	mgr.hostTemplates = inv.GetTemplatesForSelf()
+
+	// reload the host's own apply/test scripts, if any
+	mgr.reloadHostScripts(inv)
 }
 
+// ReloadVariables sources the provided variables files and returns the merged
+// variables. Files that fail to template/parse/source are skipped (and
+// logged), so that one broken file doesn't discard the variables from the
+// rest.
 func (mgr *Manager) ReloadVariables(ctx context.Context, logger *slog.Logger, paths []string, hostVars VariableMap, hostTemplates []string) VariableSlice {
-	var varMaps []VariableMap
+	// errors are logged per path while sourcing, use whatever succeeded
+	sourced, _ := mgr.sourceVariables(ctx, logger, paths, hostVars, hostTemplates)
+
+	varMaps := make([]VariableMap, len(sourced))
+	for i, s := range sourced {
+		varMaps[i] = s.Vars
+	}
+
+	return shell.MergeVariables(varMaps...)
+}
+
+// variablesKind returns the kind of inventory component (host, role, group,
+// or module) that the variables file at path belongs to, based on the
+// inventory's `<kind>s/<name>/variables` (or `<kind>s/<name>/variables.d/*`)
+// layout.
+func variablesKind(path string) string {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == inventory.VariablesDirName {
+		dir = filepath.Dir(dir)
+	}
+	kind := filepath.Base(filepath.Dir(dir))
+	switch kind {
+	case "hosts", "roles", "groups", "modules":
+		return strings.TrimSuffix(kind, "s")
+	default:
+		return "unknown"
+	}
+}
+
+// sourceVariables templates, parses, and sources each of the provided
+// variables files in order, returning the variables from each file along with
+// the path they were sourced from. Files that fail are skipped, and their
+// errors are joined and returned alongside the variables from the rest.
+func (mgr *Manager) sourceVariables(ctx context.Context, logger *slog.Logger, paths []string, hostVars VariableMap, hostTemplates []string) ([]shell.SourcedVariableMap, error) {
+	var (
+		varMaps []shell.SourcedVariableMap
+		errs    []error
+	)
 
 	for _, path := range paths {
 		allTemplateData := mgr.getTemplateData(ctx, path, hostVars, nil, hostVars)
 		renderedVars, err := templateScript(ctx, path, allTemplateData, mgr.funcMap, hostTemplates...)
 		if err != nil {
+			metricManagerTemplateRenderFailedTotal.With(prometheus.Labels{"module": path, "script": "variables"}).Inc()
+			metricManagerVariablesSourceFailedTotal.With(prometheus.Labels{"kind": variablesKind(path)}).Inc()
 			logger.LogAttrs(
 				ctx,
 				slog.LevelError,
-				"Failed to template variables",
+				"Failed to template variables, scripts will run with missing variables",
 				slog.String("err", err.Error()),
 				slog.String("path", path),
 			)
-			return nil
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			mgr.reloadTemplateErr = err
+			continue
 		}
 
 		// source variables from the templated variables file
 		file, err := syntax.NewParser().Parse(strings.NewReader(renderedVars), "")
 		if err != nil {
+			metricManagerVariablesSourceFailedTotal.With(prometheus.Labels{"kind": variablesKind(path)}).Inc()
 			logger.LogAttrs(
 				ctx,
 				slog.LevelError,
-				"Failed to parse variables",
+				"Failed to parse variables, scripts will run with missing variables",
 				slog.String("err", err.Error()),
 				slog.String("path", path),
 			)
-			return nil
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
 		}
 
 		vars, err := shell.SourceNode(ctx, file)
 		if err != nil {
+			metricManagerVariablesSourceFailedTotal.With(prometheus.Labels{"kind": variablesKind(path)}).Inc()
 			logger.LogAttrs(
 				ctx,
 				slog.LevelError,
-				"Failed to reload variables",
+				"Failed to source variables, scripts will run with missing variables",
 				slog.String("err", err.Error()),
 				slog.String("path", path),
 			)
-			return nil
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
 		}
 
-		varMaps = append(varMaps, shell.MakeVariableMap(vars))
+		varMaps = append(varMaps, shell.SourcedVariableMap{Source: path, Vars: shell.MakeVariableMap(vars)})
 	}
 
-	return shell.MergeVariables(varMaps...)
+	return varMaps, errors.Join(errs...)
 }
 
-// RunAll runs all of the Directives being managed by the Manager, followed by
-// all of the Modules being managed by the Manager.
+// VariableSources reloads the manager from the provided inventory and returns
+// the effective variables for the inventory's host, along with the path of the
+// variables file that set each variable's winning value. If a module name is
+// provided, the module's variables are included as well (and win over host
+// variables, the same as during a module run).
+func (mgr *Manager) VariableSources(ctx context.Context, logger *slog.Logger, inv inventory.Store, module string) (map[string]shell.VariableSource, error) {
+	ctx, _ = mgr.withRunContext(ctx, inv)
+	mgr.Reload(ctx, logger, inv)
+
+	sourced, err := mgr.sourceVariables(ctx, logger, inv.GetVariablesForSelf(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to source host variables: %v", err)
+	}
+
+	if module != "" {
+		mod, found := inv.GetModule(module)
+		if !found {
+			return nil, fmt.Errorf("Module %s not found in inventory", module)
+		}
+
+		if varFiles := mod.VariableFiles(); len(varFiles) > 0 {
+			modSourced, err := mgr.sourceVariables(ctx, logger, varFiles, shell.MakeVariableMap(mgr.hostVariables), mgr.hostTemplates)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to source module variables: %v", err)
+			}
+			sourced = append(sourced, modSourced...)
+		}
+	}
+
+	return shell.MergeVariablesWithSource(sourced...), nil
+}
+
+// RunAll runs all of the `pre` phase Directives being managed by the Manager,
+// followed by all of the Modules being managed by the Manager, followed by the
+// `post` phase Directives, followed by the host's own scripts (if any).
 func (mgr *Manager) RunAll(ctx context.Context, logger *slog.Logger) {
-	ctx, _ = getOrSetRunID(ctx)
+	go mgr.runAll(ctx, logger, nil, true)
+}
 
-	go func() {
-		logger.InfoContext(ctx, "Run started")
-		metricManagerRunInProgress.With(prometheus.Labels{"manager": mgr.String()}).Set(1)
+// runAll synchronously performs a run for RunAll, returning the report of the
+// run, or nil if a run was already in progress. If an inventory is provided,
+// the manager is reloaded from it once the run lock is acquired, so that a
+// reload never changes the modules/variables out from under the run in
+// progress. Queueable runs may be queued to run once the run in progress
+// finishes, see `lockRun()`.
+func (mgr *Manager) runAll(ctx context.Context, logger *slog.Logger, inv inventory.Store, queueable bool) *RunReport {
+	ctx, runID := mgr.getOrSetRunID(ctx)
+
+	// acquire the run lock before marking the run as started, so that a
+	// dropped or waiting run doesn't clobber the state of the run in
+	// progress
+	if !mgr.lockRun(ctx, logger, inv, queueable) {
+		return nil
+	}
+	defer mgr.unlockRun()
 
-		defer func() {
-			metricManagerRunInProgress.With(prometheus.Labels{"manager": mgr.String()}).Set(0)
-			logger.InfoContext(ctx, "Run rinished")
-		}()
+	if inv != nil {
+		mgr.Reload(ctx, logger, inv)
+	}
 
-		if !mgr.runLock.TryLock() {
-			logger.WarnContext(ctx, "Manager run already in progress, aborting")
-			return
-		}
-		defer mgr.runLock.Unlock()
+	// values are kept across runs for modules skipped as unchanged, but
+	// not for modules removed from the inventory
+	mgr.store.retain(func(owner string) bool {
+		_, err := mgr.modules.Vertex(owner)
+		return err == nil
+	})
 
-		directiveLogger := logger.With(
-			slog.String("runner", "directives"),
-		)
-		mgr.RunDirectives(ctx, directiveLogger)
-		moduleLogger := logger.With(
-			slog.String("runner", "modules"),
+	logger.InfoContext(ctx, "Run started")
+	metricManagerRunInProgress.With(prometheus.Labels{"manager": mgr.String()}).Set(1)
+
+	defer func() {
+		metricManagerRunInProgress.With(prometheus.Labels{"manager": mgr.String()}).Set(0)
+		logger.InfoContext(ctx, "Run rinished")
+	}()
+
+	mgr.report = newRunReport(ctx, mgr)
+	mgr.publish(ctx, Event{Type: EventRunStarted})
+	defer mgr.publish(ctx, Event{Type: EventRunFinished})
+
+	// limit the whole run to the configured budget, if any. Running
+	// scripts are canceled (and their process groups killed) once
+	// the budget is exceeded.
+	runCtx := ctx
+	timeout, err := config.GetDuration("manager.run-timeout")
+	if err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to parse run timeout, running without one",
+			slog.String("err", err.Error()),
 		)
-		mgr.RunModules(ctx, moduleLogger)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// the run is aborted early on template errors, if
+	// `manager.template-error-fatal` is enabled. Template errors while
+	// reloading variables abort the run before anything is run.
+	runCtx, mgr.abortRun = context.WithCancelCause(runCtx)
+	defer func() {
+		mgr.abortRun(nil)
+		mgr.abortRun = nil
 	}()
+	if mgr.reloadTemplateErr != nil {
+		mgr.abortOnTemplateError(ctx, logger, mgr.reloadTemplateErr)
+	}
+
+	// runs limited to a subset of modules only run the modules
+	_, subset := getModuleSubset(ctx)
+	directiveLogger := logger.With(
+		slog.String("runner", "directives"),
+	)
+	if !subset {
+		mgr.RunDirectives(runCtx, directiveLogger, inventory.DirectivePhasePre)
+	}
+	moduleLogger := logger.With(
+		slog.String("runner", "modules"),
+	)
+	mgr.RunModules(runCtx, moduleLogger)
+	if !subset {
+		mgr.RunDirectives(runCtx, directiveLogger, inventory.DirectivePhasePost)
+		hostLogger := logger.With(
+			slog.String("runner", "host"),
+		)
+		mgr.RunHostScripts(runCtx, hostLogger)
+	}
+
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		metricManagerRunTimeoutTotal.With(prometheus.Labels{"manager": mgr.String()}).Inc()
+		mgr.report.Success = false
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Run exceeded run timeout, remaining scripts were canceled",
+			slog.Duration("timeout", timeout),
+		)
+	}
+
+	report := mgr.report
+	mgr.report = nil
+	report.finish(mgr.clock.Now())
+	cleanupRunWorkDir(ctx, logger, runID, report.Success)
+	mgr.compressRunLogs(ctx, logger, runID)
+	mgr.notify(ctx, logger, report)
+
+	return report
 }
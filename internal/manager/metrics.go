@@ -50,6 +50,40 @@ var (
 		[]string{"module", "script"},
 	)
 
+	metricManagerModuleSkippedPlatformTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mango_manager_module_skipped_platform_total",
+			Help: "A count of the total number of times the module was skipped because it doesn't support the system's OS/architecture",
+		},
+		[]string{"module"},
+	)
+
+	metricManagerModuleCircuitOpen = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_manager_module_circuit_open",
+			Help: "A metric with a '1' value when the module's circuit breaker is open after repeated failures, and the module is being skipped",
+		},
+		[]string{"module"},
+	)
+
+	// template metrics
+	metricManagerTemplateRenderFailedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mango_manager_template_render_failed_total",
+			Help: "A count of the total number of failures to render templated scripts/variables. Module is the module/directive, or the path of the variables file for variables",
+		},
+		[]string{"module", "script"},
+	)
+
+	// variables metrics
+	metricManagerVariablesSourceFailedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mango_manager_variables_source_failed_total",
+			Help: "A count of the total number of failures to template, parse, or source a variables file, by the kind of inventory component the file belongs to (host/role/group/module)",
+		},
+		[]string{"kind"},
+	)
+
 	// directive run stat metrics
 	metricManagerDirectiveRunTimestamp = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -91,6 +125,14 @@ var (
 		[]string{"directive"},
 	)
 
+	metricManagerDirectiveSkippedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mango_manager_directive_skipped_total",
+			Help: "A count of the total number of times the directive was skipped because it wasn't modified within the last 24h",
+		},
+		[]string{"directive"},
+	)
+
 	// don't add runID to run-in-progress metric -- even though it could be
 	// useful, it'll hurt cardinality. Consider adding it later as a
 	// trace/examplar.
@@ -101,4 +143,64 @@ var (
 		},
 		[]string{"manager"},
 	)
+
+	metricManagerRunsQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_manager_runs_queued",
+			Help: "Number of runs queued to start once the named manager's run in progress finishes, when `manager.run-overlap` is 'queue-one'. At most 1, as further requests are coalesced",
+		},
+		[]string{"manager"},
+	)
+
+	metricManagerRunTimeoutTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mango_manager_run_timeout_total",
+			Help: "A count of the total number of runs by the named manager that were canceled for exceeding the run timeout",
+		},
+		[]string{"manager"},
+	)
+
+	metricManagerNotEnrolled = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_manager_not_enrolled",
+			Help: "A metric with a constant '1' when the named manager is skipping runs because the host is not enrolled in the inventory",
+		},
+		[]string{"manager"},
+	)
+
+	metricManagerLocallyDisabled = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mango_manager_locally_disabled",
+			Help: "A metric with a constant '1' when the named manager is skipping runs because the local `manager.disable-file` is present",
+		},
+		[]string{"manager"},
+	)
+
+	metricManagerFailureHookFailedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_manager_failure_hook_failed_total",
+			Help: "A count of the total number of times the failure hook script configured by `--manager.on-failure` failed",
+		},
+	)
+
+	metricManagerNotifyFailedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_manager_notify_failed_total",
+			Help: "A count of the total number of run notifications that failed to be sent to the configured webhook",
+		},
+	)
+
+	metricManagerRunLogsCompressFailedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_manager_run_logs_compress_failed_total",
+			Help: "A count of the total number of failures to compress the script logs of old runs",
+		},
+	)
+
+	metricManagerEventsDroppedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_manager_events_dropped_total",
+			Help: "A count of the total number of converge progress events dropped because a subscriber was not keeping up",
+		},
+	)
 )
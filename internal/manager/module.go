@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"path/filepath"
-	"time"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
 
 	"github.com/dominikbraun/graph"
+	"github.com/oklog/ulid/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
 
@@ -26,6 +30,16 @@ type Module struct {
 
 func (mod Module) String() string { return mod.m.String() }
 
+// stateVar returns the `MANGO_STATE` variable for the module's desired state
+func (mod Module) stateVar() string {
+	state := mod.m.State
+	if state == "" {
+		state = inventory.ModuleStatePresent
+	}
+
+	return "MANGO_STATE=" + state
+}
+
 // moduleHash is the hash function used to set up the directed acyclic graph
 // for module ordering/dependency management
 var moduleHash = func(m Module) string {
@@ -34,31 +48,34 @@ var moduleHash = func(m Module) string {
 
 // ReloadModules reloads the manager's modules from the specified inventory.
 func (mgr *Manager) ReloadModules(ctx context.Context, logger *slog.Logger) {
-	ctx, _ = getOrSetRunID(ctx)
+	ctx, _ = mgr.getOrSetRunID(ctx)
 
-	// get all modules from inventory applicable to this system
+	// get all modules from inventory applicable to this system, limited to
+	// the subset of modules being run, if any
 	rawMods := mgr.inv.GetModulesForSelf()
+	subset, isSubset := getModuleSubset(ctx)
+	if isSubset {
+		var subsetMods []inventory.Module
+		for _, mod := range rawMods {
+			if _, found := subset[mod.ID]; found {
+				subsetMods = append(subsetMods, mod)
+			}
+		}
+		rawMods = subsetMods
+	}
 
 	// add all modules as vertices in DAG. this must be done first before
 	// attempting to set any edges for requirements, so that we're sure the
 	// vertices already exist
 	modGraph := graph.New(moduleHash, graph.Directed(), graph.PreventCycles())
 	for _, mod := range rawMods {
-		newMod := Module{m: mod}
 		modLogger := logger.With(
 			slog.Group(
 				"module",
 				slog.String("id", mod.String()),
 			),
 		)
-
-		// if the module has a variables file set, source it and store
-		// the expanded variables
-		if mod.Variables != "" {
-			newMod.Variables = mgr.ReloadVariables(ctx, modLogger, []string{mod.Variables}, shell.MakeVariableMap(mgr.hostVariables), mgr.hostTemplates)
-		} else {
-			modLogger.DebugContext(ctx, "No module variables")
-		}
+		newMod := mgr.newModule(ctx, modLogger, mod)
 
 		err := modGraph.AddVertex(newMod)
 		if err != nil {
@@ -91,7 +108,18 @@ func (mgr *Manager) ReloadModules(ctx context.Context, logger *slog.Logger) {
 					slog.String("path", mod.Requires),
 				)
 			} else {
-				err := modGraph.AddEdge(filepath.Join(mgr.inv.GetInventoryPath(), "modules", line.Text), mod.ID)
+				if _, found := subset[line.Text]; isSubset && !found {
+					logger.LogAttrs(
+						ctx,
+						slog.LevelDebug,
+						"Ignoring module requirement outside of the modules being run",
+						slog.String("module", mod.ID),
+						slog.String("requires", line.Text),
+					)
+					continue
+				}
+
+				err := modGraph.AddEdge(line.Text, mod.ID)
 				if err != nil {
 					logger.LogAttrs(
 						ctx,
@@ -107,10 +135,247 @@ func (mgr *Manager) ReloadModules(ctx context.Context, logger *slog.Logger) {
 	mgr.modules = modGraph
 }
 
+// newModule wraps the provided inventory module, sourcing and storing the
+// expanded variables from the module's variables file, if set.
+func (mgr *Manager) newModule(ctx context.Context, logger *slog.Logger, mod inventory.Module) Module {
+	newMod := Module{m: mod}
+
+	if varFiles := mod.VariableFiles(); len(varFiles) > 0 {
+		newMod.Variables = mgr.ReloadVariables(ctx, logger, varFiles, shell.MakeVariableMap(mgr.hostVariables), mgr.hostTemplates)
+	} else {
+		logger.DebugContext(ctx, "No module variables")
+	}
+
+	return newMod
+}
+
+// moduleRunData assembles the variables, template data, and user defined
+// template files used to render and run the module's scripts.
+func (mgr *Manager) moduleRunData(ctx context.Context, mod Module) (VariableSlice, templateView, []string) {
+	hostVarsMap := shell.MakeVariableMap(mgr.hostVariables)
+	modVarsMap := shell.MakeVariableMap(mod.Variables)
+	// facts from modules earlier in the run take precedence over host
+	// variables, but not the module's own variables
+	allVars := shell.MergeVariables(hostVarsMap, mgr.runFacts, modVarsMap)
+	// module's literal env vars are appended last, so they take precedence
+	// over host and module variables in the script's environment, followed
+	// only by the module's state, which is always set by mango
+	allVars = append(allVars, mod.m.Env...)
+	allVars = append(allVars, mod.stateVar())
+	allVarsMap := shell.MakeVariableMap(allVars)
+	allTemplateData := mgr.getTemplateData(ctx, mod.String(), hostVarsMap, modVarsMap, allVarsMap)
+	allTemplateData.Mango.Metadata.ModuleState = mod.m.State
+	allUserTemplateFiles := slices.Concat(mgr.hostTemplates, mod.m.TemplateFiles)
+
+	return allVars, allTemplateData, allUserTemplateFiles
+}
+
+// RenderModuleScript reloads the manager from the provided inventory and
+// returns the rendered contents of the named module's script (`apply` or
+// `test`) without running it. Run specific template metadata (run ID,
+// enrollment status, etc) is populated the same way as for a real run, so that
+// modules can be rendered offline.
+func (mgr *Manager) RenderModuleScript(ctx context.Context, logger *slog.Logger, inv inventory.Store, module, script string) (string, error) {
+	ctx, _ = mgr.withRunContext(ctx, inv)
+	mgr.Reload(ctx, logger, inv)
+
+	invMod, found := inv.GetModule(module)
+	if !found {
+		return "", fmt.Errorf("Module %s not found in inventory", module)
+	}
+
+	var path string
+	switch script {
+	case "apply":
+		path = invMod.Apply
+	case "test":
+		path = invMod.Test
+	default:
+		return "", fmt.Errorf("Unsupported module script %s, must be one of: [apply, test]", script)
+	}
+
+	if path == "" {
+		return "", fmt.Errorf("Module %s has no %s script", module, script)
+	}
+
+	mod := mgr.newModule(ctx, logger, invMod)
+	_, allTemplateData, allUserTemplateFiles := mgr.moduleRunData(ctx, mod)
+
+	return templateScript(ctx, path, allTemplateData, mgr.funcMap, allUserTemplateFiles...)
+}
+
+// ModuleTestResult is the result of running a single module's `test` script
+// with TestModules.
+type ModuleTestResult struct {
+	Module     string
+	ExitCode   uint8
+	StderrTail string
+	// Err is set if the test script couldn't be rendered/run
+	Err error
+	// Skipped is the reason the module's test wasn't run, if it was skipped
+	Skipped string
+}
+
+// Passed reports whether the module's test ran and exited successfully.
+// Skipped modules are not considered failures.
+func (r ModuleTestResult) Passed() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// TestModules reloads the manager from the provided inventory and runs only
+// the `test` scripts of the host's modules, in dependency order, without
+// applying anything. Run specific template metadata is populated the same way
+// as for a real run.
+func (mgr *Manager) TestModules(ctx context.Context, logger *slog.Logger, inv inventory.Store) ([]ModuleTestResult, error) {
+	ctx, runID := mgr.withRunContext(ctx, inv)
+	mgr.Reload(ctx, logger, inv)
+
+	order, err := graph.TopologicalSort(mgr.modules)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sort modules: %v", err)
+	}
+
+	results := make([]ModuleTestResult, 0, len(order))
+	for _, v := range order {
+		res := ModuleTestResult{Module: v}
+		vLogger := logger.With(
+			slog.Group(
+				"module",
+				slog.String("id", v),
+			),
+		)
+
+		mod, err := mgr.modules.Vertex(v)
+		switch {
+		case err != nil:
+			res.Err = fmt.Errorf("Failed to retreive module from directed acyclic graph vertex: %v", err)
+		case mod.m.Test == "":
+			res.Skipped = "no test script"
+		case !mgr.moduleSupportsPlatform(mod):
+			res.Skipped = "platform not supported"
+		default:
+			res.ExitCode, res.StderrTail, res.Err = mgr.testModule(ctx, vLogger, runID, mod)
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// testModule renders and runs the module's `test` script.
+func (mgr *Manager) testModule(ctx context.Context, logger *slog.Logger, runID ulid.ULID, mod Module) (uint8, string, error) {
+	ms, err := mgr.prepareModuleScripts(ctx, logger, mod)
+	if err != nil {
+		return 1, "", err
+	}
+
+	rendered, err := mgr.renderModuleScript(ctx, mod, ms, "test", mod.m.Test)
+	if err != nil {
+		return 1, "", err
+	}
+
+	return mgr.runModuleScript(ctx, runID, mod, ms, "test", mod.m.Test, rendered)
+}
+
+// moduleScripts contains everything needed to render and run a module's
+// scripts, shared by the module's `test` and `apply` scripts.
+type moduleScripts struct {
+	vars          VariableSlice
+	view          templateView
+	templateFiles []string
+	limits        shell.Limits
+	maxOutputSize uint64
+	workDir       string
+	become        *shell.Credential
+	stdin         *string
+	tailLines     int
+	trustedPaths  []string
+}
+
+// prepareModuleScripts assembles the variables, template data, limits, working
+// directory, etc, that the module's scripts are rendered and run with.
+func (mgr *Manager) prepareModuleScripts(ctx context.Context, logger *slog.Logger, mod Module) (moduleScripts, error) {
+	var (
+		ms  moduleScripts
+		err error
+	)
+
+	ms.vars, ms.view, ms.templateFiles = mgr.moduleRunData(ctx, mod)
+	ms.limits = getScriptLimits(ctx, logger, mod.m.Limits)
+	ms.maxOutputSize = getMaxOutputSize(ctx, logger)
+	ms.workDir, err = mgr.getModuleWorkDir(ctx, mod, ms.view, ms.templateFiles)
+	if err != nil {
+		return ms, err
+	}
+	ms.stdin, err = mgr.getModuleStdin(ctx, mod, ms.view, ms.templateFiles)
+	if err != nil {
+		return ms, err
+	}
+	if mod.m.Become != "" {
+		ms.become, err = shell.ParseBecomeFile(mod.m.Become)
+		if err != nil {
+			return ms, fmt.Errorf("Failed to switch to module become user: %v", err)
+		}
+	}
+	ms.tailLines = viper.GetInt("manager.stderr-tail-lines")
+
+	return ms, nil
+}
+
+// renderModuleScript renders the module's script (`test` or `apply`).
+func (mgr *Manager) renderModuleScript(ctx context.Context, mod Module, ms moduleScripts, script, path string) (string, error) {
+	rendered, err := templateScript(ctx, path, ms.view, mgr.funcMap, ms.templateFiles...)
+	if err != nil {
+		metricManagerTemplateRenderFailedTotal.With(prometheus.Labels{"module": mod.String(), "script": script}).Inc()
+		return "", fmt.Errorf("Failed to template script: %w", err)
+	}
+
+	return rendered, nil
+}
+
+// runModuleScript runs the module's rendered script (`test` or `apply`),
+// returning the exit code and the tail of the script's stderr.
+func (mgr *Manager) runModuleScript(ctx context.Context, runID ulid.ULID, mod Module, ms moduleScripts, script, path, rendered string) (uint8, string, error) {
+	stderr := shell.NewTailBuffer(ms.tailLines)
+	opts := mgr.runOptions
+	opts.Vars = ms.vars
+	opts.Limits = ms.limits
+	opts.MaxOutputSize = ms.maxOutputSize
+	opts.WorkDir = ms.workDir
+	opts.Become = ms.become
+	opts.Stdin = stdinReader(ms.stdin)
+	opts.StderrCapture = stderr
+	opts.Store = mgr.store.forModule(mod.String(), runID)
+	opts.TrustedPaths = ms.trustedPaths
+	rc, err := shell.RunWithOptions(ctx, runID, path, rendered, opts)
+	if err == nil {
+		mgr.publish(ctx, Event{Type: EventScriptFinished, Module: mod.String(), Script: script, ExitCode: &rc})
+	}
+
+	return rc, stderr.String(), err
+}
+
+// exitCodeError is returned when a module's apply script exits with a
+// non-zero exit code, so that the exit code is available to callers (ie, the
+// failure hook).
+type exitCodeError struct {
+	code   uint8
+	stderr string
+}
+
+func (e exitCodeError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("Failed to run module apply, non-zero exit code returned: %d, last lines of stderr: %q", e.code, e.stderr)
+	}
+
+	return fmt.Sprintf("Failed to run module apply, non-zero exit code returned: %d", e.code)
+}
+
 // RunModule is responsible for actually executing a module, using the `shell`
 // package.
 func (mgr *Manager) RunModule(ctx context.Context, logger *slog.Logger, mod Module) error {
-	ctx, runID := getOrSetRunID(ctx)
+	ctx, runID := mgr.getOrSetRunID(ctx)
 
 	if mod.m.Apply == "" {
 		return fmt.Errorf("Module has no apply script")
@@ -121,12 +386,10 @@ func (mgr *Manager) RunModule(ctx context.Context, logger *slog.Logger, mod Modu
 		"script": "",
 	}
 
-	hostVarsMap := shell.MakeVariableMap(mgr.hostVariables)
-	modVarsMap := shell.MakeVariableMap(mod.Variables)
-	allVars := shell.MergeVariables(hostVarsMap, modVarsMap)
-	allVarsMap := shell.MakeVariableMap(allVars)
-	allTemplateData := mgr.getTemplateData(ctx, mod.String(), hostVarsMap, modVarsMap, allVarsMap)
-	allUserTemplateFiles := append(mgr.hostTemplates, mod.m.TemplateFiles...)
+	ms, err := mgr.prepareModuleScripts(ctx, logger, mod)
+	if err != nil {
+		return err
+	}
 
 	var testRC uint8
 	if mod.m.Test == "" {
@@ -136,18 +399,19 @@ func (mgr *Manager) RunModule(ctx context.Context, logger *slog.Logger, mod Modu
 			"Module has no test script, proceeding to apply",
 		)
 	} else {
-		testStart := time.Now()
+		testStart := mgr.clock.Now()
 		labels["script"] = "test"
 		metricManagerModuleRunTimestamp.With(labels).Set(float64(testStart.Unix()))
 
-		renderedTest, err := templateScript(ctx, mod.m.Test, allTemplateData, mgr.funcMap, allUserTemplateFiles...)
+		renderedTest, err := mgr.renderModuleScript(ctx, mod, ms, "test", mod.m.Test)
 		if err != nil {
-			return fmt.Errorf("Failed to template script: %s", err)
+			return err
 		}
 
-		testRC, err = shell.Run(ctx, runID, mod.m.Test, renderedTest, allVars)
+		var testStderr string
+		testRC, testStderr, err = mgr.runModuleScript(ctx, runID, mod, ms, "test", mod.m.Test, renderedTest)
 		// update metrics regardless of error, so do them before handling error
-		metricManagerModuleRunDuration.With(labels).Observe(float64(time.Since(testStart).Seconds()))
+		metricManagerModuleRunDuration.With(labels).Observe(float64(mgr.since(testStart).Seconds()))
 		metricManagerModuleRunTotal.With(labels).Inc()
 		switch {
 		case err != nil:
@@ -167,34 +431,55 @@ func (mgr *Manager) RunModule(ctx context.Context, logger *slog.Logger, mod Modu
 				slog.LevelWarn,
 				"Failed to run module test, received non-zero exit code",
 				slog.Any("exit_code", testRC),
+				slog.String("stderr_tail", testStderr),
 			)
 		default:
 			metricManagerModuleRunSuccessTimestamp.With(labels).Set(float64(testStart.Unix()))
 		}
 	}
 
-	if viper.GetBool("manager.skip-apply-on-test-success") && mod.m.Test != "" && testRC == 0 {
+	// module level setting takes precedence over the global flag
+	skipApply := viper.GetBool("manager.skip-apply-on-test-success")
+	if mod.m.SkipApplyOnTestSuccess != nil {
+		skipApply = *mod.m.SkipApplyOnTestSuccess
+	}
+
+	if skipApply && mod.m.Test != "" && testRC == 0 {
 		logger.LogAttrs(
 			ctx,
 			slog.LevelDebug,
-			"Skipping module apply script because test script ran successfully and mango has been started with flag `--manager.skip-apply-on-test-success`",
+			"Skipping module apply script because test script ran successfully and skip-apply-on-test-success is enabled for the module",
 		)
 
 		return nil
 	}
 
-	applyStart := time.Now()
+	applyStart := mgr.clock.Now()
 	labels["script"] = "apply"
 	metricManagerModuleRunTimestamp.With(labels).Set(float64(applyStart.Unix()))
 
-	renderedApply, err := templateScript(ctx, mod.m.Apply, allTemplateData, mgr.funcMap, allUserTemplateFiles...)
+	// facts and stored values from a previous apply are stale once the
+	// module is applied again, regardless of the result
+	mgr.recordModuleFacts(mod.String(), nil)
+	mgr.store.dropStale(mod.String(), runID)
+
+	renderedApply, err := mgr.renderModuleScript(ctx, mod, ms, "apply", mod.m.Apply)
 	if err != nil {
-		return fmt.Errorf("Failed to template script: %s", err)
+		return err
 	}
 
-	applyRC, err := shell.Run(ctx, runID, mod.m.Apply, renderedApply, allVars)
+	// the apply script may write facts to share with later modules
+	factsFile, err := createFactsFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(factsFile)
+	ms.vars = append(slices.Clone(ms.vars), "MANGO_FACTS="+factsFile)
+	ms.trustedPaths = []string{factsFile}
+
+	applyRC, applyStderr, err := mgr.runModuleScript(ctx, runID, mod, ms, "apply", mod.m.Apply, renderedApply)
 	// update metrics regardless of error, so do them before handling error
-	metricManagerModuleRunDuration.With(labels).Observe(float64(time.Since(applyStart).Seconds()))
+	metricManagerModuleRunDuration.With(labels).Observe(float64(mgr.since(applyStart).Seconds()))
 	metricManagerModuleRunTotal.With(labels).Inc()
 	switch {
 	case err != nil:
@@ -203,22 +488,42 @@ func (mgr *Manager) RunModule(ctx context.Context, logger *slog.Logger, mod Modu
 	case applyRC != 0:
 		// if apply script for a module fails, log a warning for user and continue with apply
 		metricManagerModuleRunFailedTotal.With(labels).Inc()
-		return fmt.Errorf("Failed to run module apply, non-zero exit code returned: %d", applyRC)
+		return exitCodeError{code: applyRC, stderr: applyStderr}
 	default:
 		metricManagerModuleRunSuccessTimestamp.With(labels).Set(float64(applyStart.Unix()))
 	}
 
+	facts, err := readFactsFile(ctx, logger, factsFile)
+	if err != nil {
+		return err
+	}
+	if len(facts) > 0 {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelDebug,
+			"Recorded module facts",
+			slog.Int("count", len(facts)),
+		)
+	}
+	mgr.recordModuleFacts(mod.String(), facts)
+
 	return nil
 }
 
 // RunModules runs all of the modules being managed by the Manager
 func (mgr *Manager) RunModules(ctx context.Context, logger *slog.Logger) {
-	ctx, _ = getOrSetRunID(ctx)
+	ctx, _ = mgr.getOrSetRunID(ctx)
 
 	logger.InfoContext(ctx, "Module run started")
 	defer logger.InfoContext(ctx, "Module run finished")
 
-	order, err := graph.TopologicalSort(mgr.modules)
+	sortFunc := graph.TopologicalSort[string, Module]
+	if viper.GetBool("manager.randomize-order") {
+		logger.DebugContext(ctx, "Randomizing order of modules that don't depend on each other")
+		sortFunc = shuffledTopologicalSort
+	}
+
+	order, err := sortFunc(mgr.modules)
 	if err != nil {
 		logger.LogAttrs(
 			ctx,
@@ -233,7 +538,21 @@ func (mgr *Manager) RunModules(ctx context.Context, logger *slog.Logger) {
 		return
 	}
 
-	for _, v := range order {
+	// only run modules that have changed since their last successful run
+	// (and anything that depends on them), unless a full converge is forced
+	toRun := mgr.getModulesToRun(ctx, logger, order)
+
+	// facts are accumulated in run order, so that each module sees the
+	// facts of the modules before it, whether they were run or skipped
+	// (as unchanged modules keep their facts from their last apply)
+	mgr.runFacts = make(VariableMap)
+	defer func() { mgr.runFacts = nil }()
+
+	for i, v := range order {
+		if i > 0 {
+			mgr.mergeModuleFacts(order[i-1])
+		}
+
 		vLogger := logger.With(
 			slog.Group(
 				"module",
@@ -241,6 +560,26 @@ func (mgr *Manager) RunModules(ctx context.Context, logger *slog.Logger) {
 			),
 		)
 
+		fingerprint, found := toRun[v]
+		if found && ctx.Err() != nil {
+			// run was canceled (ie, run timeout exceeded, or aborted
+			// after a fatal template error), forget the fingerprint so
+			// the module is retried on the next run
+			delete(mgr.moduleFingerprints, v)
+			mgr.publish(ctx, Event{Type: EventModuleFinished, Module: v, Error: context.Cause(ctx).Error()})
+			vLogger.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"Run canceled, skipping module",
+				slog.String("err", context.Cause(ctx).Error()),
+			)
+			continue
+		}
+		if !found {
+			vLogger.DebugContext(ctx, "Module unchanged since last successful run, skipping")
+			continue
+		}
+
 		mod, err := mgr.modules.Vertex(v)
 		if err != nil {
 			vLogger.LogAttrs(
@@ -251,16 +590,95 @@ func (mgr *Manager) RunModules(ctx context.Context, logger *slog.Logger) {
 			)
 		}
 
+		if !mgr.moduleSupportsPlatform(mod) {
+			metricManagerModuleSkippedPlatformTotal.With(prometheus.Labels{"module": v}).Inc()
+			vLogger.LogAttrs(
+				ctx,
+				slog.LevelInfo,
+				"Module doesn't support this platform, skipping",
+				slog.String("os", mgr.tmplData.OS.ID()),
+				slog.String("arch", runtime.GOARCH),
+				slog.String("supported_os", strings.Join(mod.m.SupportedOS, ",")),
+				slog.String("supported_arch", strings.Join(mod.m.SupportedArch, ",")),
+			)
+			continue
+		}
+
+		if mgr.circuitOpen(ctx, vLogger, v) {
+			continue
+		}
+
 		vLogger.InfoContext(ctx, "Module started")
 		defer vLogger.InfoContext(ctx, "Module finished")
+		mgr.publish(ctx, Event{Type: EventModuleStarted, Module: v})
 
-		if err := mgr.RunModule(ctx, vLogger, mod); err != nil {
+		err = mgr.RunModule(ctx, vLogger, mod)
+		mgr.publish(ctx, Event{Type: EventModuleFinished, Module: v, Error: errString(err)})
+		mgr.recordModuleResult(ctx, vLogger, v, err)
+		if err != nil {
+			// forget the fingerprint so the module is retried on the next run
+			delete(mgr.moduleFingerprints, v)
 			vLogger.LogAttrs(
 				ctx,
 				slog.LevelError,
 				"Module failed",
 				slog.String("err", err.Error()),
 			)
+			mgr.runFailureHook(ctx, vLogger, v, err)
+			mgr.abortOnTemplateError(ctx, vLogger, err)
+			continue
+		}
+
+		if fingerprint != "" {
+			mgr.moduleFingerprints[v] = fingerprint
+		}
+	}
+}
+
+// shuffledTopologicalSort returns a topological ordering of the modules in the
+// graph, picking at random from the set of modules whose requirements have
+// already been satisfied at each step. Module requirements are always
+// respected, but the order of modules that don't depend on each other will
+// differ between runs, which helps surface missing `requires` declarations.
+func shuffledTopologicalSort(g graph.Graph[string, Module]) ([]string, error) {
+	predecessors, err := g.PredecessorMap()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get predecessor map: %v", err)
+	}
+
+	adjacency, err := g.AdjacencyMap()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get adjacency map: %v", err)
+	}
+
+	var ready []string
+	inDegree := make(map[string]int, len(predecessors))
+	for v, preds := range predecessors {
+		inDegree[v] = len(preds)
+		if len(preds) == 0 {
+			ready = append(ready, v)
+		}
+	}
+
+	order := make([]string, 0, len(predecessors))
+	for len(ready) > 0 {
+		i := rand.IntN(len(ready))
+		v := ready[i]
+		ready[i] = ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+
+		order = append(order, v)
+		for next := range adjacency[v] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				ready = append(ready, next)
+			}
 		}
 	}
+
+	if len(order) != len(predecessors) {
+		return nil, fmt.Errorf("Failed to sort modules, graph contains a cycle")
+	}
+
+	return order, nil
 }
@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	notifyTimeout        = 10 * time.Second
+	notifyInitialBackoff = time.Second
+)
+
+// notify posts the run report to the webhook configured by `manager.notify`,
+// if any. The body is the JSON encoded report, unless a template is configured
+// with `manager.notify-template`, in which case the template is executed with
+// the report as its data. Transient failures (connection errors, 429s, and
+// 5xx responses) are retried with exponential backoff.
+func (mgr *Manager) notify(ctx context.Context, logger *slog.Logger, report *RunReport) {
+	url := viper.GetString("manager.notify")
+	if url == "" {
+		return
+	}
+
+	nLogger := logger.With(slog.String("webhook", url))
+
+	body, err := notifyBody(report)
+	if err != nil {
+		metricManagerNotifyFailedTotal.Inc()
+		nLogger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to build run notification",
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	retries := max(viper.GetInt("manager.notify-retries"), 0)
+	backoff := notifyInitialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := postNotification(ctx, url, body)
+		if err == nil {
+			nLogger.DebugContext(ctx, "Sent run notification")
+			return
+		}
+
+		if !retry || attempt >= retries {
+			metricManagerNotifyFailedTotal.Inc()
+			nLogger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"Failed to send run notification",
+				slog.String("err", err.Error()),
+				slog.Int("attempts", attempt+1),
+			)
+			return
+		}
+
+		nLogger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"Failed to send run notification, retrying",
+			slog.String("err", err.Error()),
+			slog.Duration("backoff", backoff),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func notifyBody(report *RunReport) ([]byte, error) {
+	tmplPath := viper.GetString("manager.notify-template")
+	if tmplPath == "" {
+		body, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode run report: %v", err)
+		}
+
+		return body, nil
+	}
+
+	t, err := template.New(filepath.Base(tmplPath)).ParseFiles(tmplPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse notification template %s: %v", tmplPath, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, report); err != nil {
+		return nil, fmt.Errorf("Failed to execute notification template %s: %v", tmplPath, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// postNotification posts the body to the url, returning an error if it fails
+// and whether or not the failure is transient and should be retried.
+func postNotification(ctx context.Context, url string, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("Failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("Failed to post to webhook: %v", err)
+	}
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests, res.StatusCode >= 500:
+		return true, fmt.Errorf("Webhook returned transient failure status code: %d", res.StatusCode)
+	case res.StatusCode >= 300:
+		return false, fmt.Errorf("Webhook returned failure status code: %d", res.StatusCode)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"runtime"
+	"slices"
+)
+
+// archAliases maps common alternate names for CPU architectures (ie, from
+// `uname -m`) to the Go architecture names used for comparison.
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+// moduleSupportsPlatform reports whether the module can run on this system,
+// based on its `supported-os` and `supported-arch` files. An OS entry matches
+// the OS type (ie, "linux"), the os-release `ID` or any `ID_LIKE` entry, or
+// the distribution family. An arch entry matches the Go architecture (ie,
+// "amd64"), or a common alias for it (ie, "x86_64"). Modules that don't
+// restrict their platforms are always supported.
+func (mgr *Manager) moduleSupportsPlatform(mod Module) bool {
+	osMatches := len(mod.m.SupportedOS) == 0 || slices.ContainsFunc(mod.m.SupportedOS, func(name string) bool {
+		return name == runtime.GOOS || mgr.tmplData.OS.IsDistro(name) || name == mgr.tmplData.OS.Family()
+	})
+
+	archMatches := len(mod.m.SupportedArch) == 0 || slices.ContainsFunc(mod.m.SupportedArch, func(name string) bool {
+		if alias, found := archAliases[name]; found {
+			name = alias
+		}
+		return name == runtime.GOARCH
+	})
+
+	return osMatches && archMatches
+}
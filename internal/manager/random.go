@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"text/template"
+)
+
+const (
+	randomLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	randomNumbers = "0123456789"
+)
+
+// seededRandomFuncs returns template functions that override sprout's
+// `random` and `uniqueid` registries with deterministic equivalents, for use
+// when `manager.template-seed` is set. The random source is seeded from the
+// configured seed, the hostname, and the module name, so that repeated renders
+// of the same module on the same host produce the same output, while
+// different hosts and modules still get different values.
+func seededRandomFuncs(seed string, md metadata) template.FuncMap {
+	rng := rand.NewChaCha8(sha256.Sum256([]byte(seed + "\x00" + md.Hostname + "\x00" + md.ModuleName)))
+	r := rand.New(rng)
+
+	randomString := func(count int, chars string) string {
+		if count <= 0 {
+			return ""
+		}
+
+		var sb strings.Builder
+		sb.Grow(count)
+		for range count {
+			sb.WriteByte(chars[r.IntN(len(chars))])
+		}
+
+		return sb.String()
+	}
+
+	var ascii strings.Builder
+	for c := byte(32); c <= 126; c++ {
+		ascii.WriteByte(c)
+	}
+	randomASCII := ascii.String()
+
+	return template.FuncMap{
+		"randAlphaNum": func(count int) string { return randomString(count, randomLetters+randomNumbers) },
+		"randAlpha":    func(count int) string { return randomString(count, randomLetters) },
+		"randAscii":    func(count int) string { return randomString(count, randomASCII) },
+		"randNumeric":  func(count int) string { return randomString(count, randomNumbers) },
+		"randBytes": func(count int) (string, error) {
+			if count <= 0 {
+				return "", nil
+			}
+
+			buf := make([]byte, count)
+			_, _ = rng.Read(buf) // never returns an error
+			return base64.StdEncoding.EncodeToString(buf), nil
+		},
+		"randInt": func(min, max int) int { return r.IntN(max-min) + min },
+		"uuidv4": func() string {
+			var u [16]byte
+			_, _ = rng.Read(u[:])       // never returns an error
+			u[6] = (u[6] & 0x0f) | 0x40 // version 4
+			u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+			return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"time"
+)
+
+// RunReport summarizes the results of a manager run, for use in run
+// notifications.
+type RunReport struct {
+	Manager    string            `json:"manager"`
+	Hostname   string            `json:"hostname"`
+	RunID      string            `json:"run_id"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Duration   float64           `json:"duration_seconds"`
+	Success    bool              `json:"success"`
+	Directives []RunReportResult `json:"directives"`
+	Modules    []RunReportResult `json:"modules"`
+}
+
+// RunReportResult is the result of a single directive or module (including
+// the host's own scripts) that was run during a manager run. Modules that
+// were skipped because they were unchanged are not included.
+type RunReportResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newRunReport(ctx context.Context, mgr *Manager) *RunReport {
+	md := getRunMetadata(ctx, "")
+
+	return &RunReport{
+		Manager:    mgr.String(),
+		Hostname:   md.Hostname,
+		RunID:      md.RunID,
+		Start:      mgr.clock.Now(),
+		Success:    true,
+		Directives: []RunReportResult{},
+		Modules:    []RunReportResult{},
+	}
+}
+
+// record updates the report with the results in the given event.
+func (r *RunReport) record(e Event) {
+	res := RunReportResult{Success: e.Error == "", Error: e.Error}
+
+	switch e.Type {
+	case EventDirectiveFinished:
+		res.ID = e.Directive
+		r.Directives = append(r.Directives, res)
+	case EventModuleFinished:
+		res.ID = e.Module
+		r.Modules = append(r.Modules, res)
+	default:
+		return
+	}
+
+	if !res.Success {
+		r.Success = false
+	}
+}
+
+func (r *RunReport) finish(end time.Time) {
+	r.End = end
+	r.Duration = r.End.Sub(r.Start).Seconds()
+}
@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// getModuleStdin renders the module's stdin file (if any) and returns the
+// contents to provide as stdin to the module's scripts. A nil pointer is
+// returned if the module doesn't set stdin, so that an empty stdin file can
+// still be told apart from no stdin at all.
+func (mgr *Manager) getModuleStdin(ctx context.Context, mod Module, view templateView, userTemplateFiles []string) (*string, error) {
+	if mod.m.Stdin == "" {
+		return nil, nil
+	}
+
+	rendered, err := templateScript(ctx, mod.m.Stdin, view, mgr.funcMap, userTemplateFiles...)
+	if err != nil {
+		metricManagerTemplateRenderFailedTotal.With(prometheus.Labels{"module": mod.String(), "script": "stdin"}).Inc()
+		return nil, fmt.Errorf("Failed to template module stdin: %w", err)
+	}
+
+	return &rendered, nil
+}
+
+// stdinReader returns a new reader for the provided stdin contents, so that
+// each script reads stdin from the start, or nil if stdin isn't set.
+func stdinReader(stdin *string) io.Reader {
+	if stdin == nil {
+		return nil
+	}
+
+	return strings.NewReader(*stdin)
+}
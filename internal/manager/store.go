@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"sync"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/tjhop/mango/internal/shell"
+)
+
+// runStore is the key-value store shared by the modules of a run, so that
+// modules can pass computed values (ie, a generated password or discovered
+// port) to the modules run after them without writing them to disk. Scripts
+// access it with the `mango-get`/`mango-set` commands, and templates with the
+// `mangoGet` function. Like facts, values are kept until the module that set
+// them is applied again, so that modules skipped as unchanged continue to
+// provide their values to later modules.
+type runStore struct {
+	mu   sync.RWMutex
+	data map[string]storeEntry
+}
+
+// storeEntry is a value in the store, along with the module that set it and
+// the run it was set in.
+type storeEntry struct {
+	value string
+	owner string
+	runID ulid.ULID
+}
+
+func newRunStore() *runStore {
+	return &runStore{data: make(map[string]storeEntry)}
+}
+
+// Get returns the value of the key, and whether it's set.
+func (s *runStore) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, found := s.data[key]
+	return entry.value, found
+}
+
+// set sets the key to the value, owned by the module.
+func (s *runStore) set(owner string, runID ulid.ULID, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = storeEntry{value: value, owner: owner, runID: runID}
+}
+
+// forModule returns a view of the store for the module's scripts in the run,
+// recording the module as the owner of the values it sets.
+func (s *runStore) forModule(owner string, runID ulid.ULID) shell.Store {
+	return moduleStore{store: s, owner: owner, runID: runID}
+}
+
+// dropStale removes the values set by the module in previous runs, so that
+// values set by its test script in the current run are kept for its apply.
+func (s *runStore) dropStale(owner string, runID ulid.ULID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.data {
+		if entry.owner == owner && entry.runID != runID {
+			delete(s.data, key)
+		}
+	}
+}
+
+// retain removes the values set by modules that no longer exist.
+func (s *runStore) retain(exists func(owner string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.data {
+		if !exists(entry.owner) {
+			delete(s.data, key)
+		}
+	}
+}
+
+// moduleStore is a module's view of the run store.
+type moduleStore struct {
+	store *runStore
+	owner string
+	runID ulid.ULID
+}
+
+// Get returns the value of the key, and whether it's set.
+func (m moduleStore) Get(key string) (string, bool) {
+	return m.store.Get(key)
+}
+
+// Set sets the key to the value, owned by the module.
+func (m moduleStore) Set(key, value string) {
+	m.store.set(m.owner, m.runID, key, value)
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestRunStoreKeepsValuesUntilOwnerReapplied(t *testing.T) {
+	s := newRunStore()
+	firstRun, secondRun := ulid.Make(), ulid.Make()
+
+	s.forModule("producer", firstRun).Set("PASSWORD", "secret")
+	s.forModule("other", firstRun).Set("PORT", "8080")
+
+	// the producer is skipped in the next run, so its values remain
+	if got, found := s.forModule("consumer", secondRun).Get("PASSWORD"); !found || got != "secret" {
+		t.Errorf("Get(PASSWORD) = %q, %t, want %q, true", got, found, "secret")
+	}
+
+	// values set by the producer's test script in the current run are
+	// kept when its apply runs, previous values are dropped
+	s.forModule("producer", secondRun).Set("TESTED", "true")
+	s.dropStale("producer", secondRun)
+	if _, found := s.Get("PASSWORD"); found {
+		t.Error("Get(PASSWORD) found after producer was reapplied, want dropped")
+	}
+	if _, found := s.Get("TESTED"); !found {
+		t.Error("Get(TESTED) not found, want value set in the current run kept")
+	}
+	if _, found := s.Get("PORT"); !found {
+		t.Error("Get(PORT) not found, want other module's value kept")
+	}
+
+	// values from modules removed from the inventory are dropped
+	s.retain(func(owner string) bool { return owner == "producer" })
+	if _, found := s.Get("PORT"); found {
+		t.Error("Get(PORT) found after module was removed, want dropped")
+	}
+	if _, found := s.Get("TESTED"); !found {
+		t.Error("Get(TESTED) not found, want remaining module's value kept")
+	}
+}
@@ -4,43 +4,150 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	kernelParser "github.com/moby/moby/pkg/parsers/kernel"
 	"github.com/prometheus/procfs"
 	"github.com/prometheus/procfs/blockdevice"
 	distro "github.com/quay/claircore/osrelease"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+
+	"github.com/tjhop/mango/pkg/utils"
 )
 
 // general
 
 const (
-	procDir = "/proc"
-	sysDir  = "/sys"
+	defaultProcDir = "/proc"
+	defaultSysDir  = "/sys"
 )
 
+// procDir returns the path procfs is mounted at, which may be overridden with
+// `manager.procfs-path` (ie, to collect metadata from a host's procfs mounted
+// into a container, or from fixture data).
+func procDir() string {
+	if dir := viper.GetString("manager.procfs-path"); dir != "" {
+		return dir
+	}
+
+	return defaultProcDir
+}
+
+// sysDir returns the path sysfs is mounted at, which may be overridden with
+// `manager.sysfs-path`.
+func sysDir() string {
+	if dir := viper.GetString("manager.sysfs-path"); dir != "" {
+		return dir
+	}
+
+	return defaultSysDir
+}
+
 // OS metadata
 
+// distroFamilies maps well known os-release IDs to the distribution family
+// they belong to.
+var distroFamilies = map[string]string{
+	"debian":              "debian",
+	"ubuntu":              "debian",
+	"linuxmint":           "debian",
+	"pop":                 "debian",
+	"raspbian":            "debian",
+	"rhel":                "rhel",
+	"centos":              "rhel",
+	"fedora":              "rhel",
+	"rocky":               "rhel",
+	"almalinux":           "rhel",
+	"ol":                  "rhel",
+	"amzn":                "rhel",
+	"suse":                "suse",
+	"sles":                "suse",
+	"opensuse":            "suse",
+	"opensuse-leap":       "suse",
+	"opensuse-tumbleweed": "suse",
+	"arch":                "arch",
+	"manjaro":             "arch",
+	"endeavouros":         "arch",
+	"alpine":              "alpine",
+	"gentoo":              "gentoo",
+}
+
 type osMetadata struct {
 	OSRelease map[string]string
 }
 
+// ID returns the os-release `ID` field, defaulting to "linux" if unset as per
+// the os-release spec.
+func (o osMetadata) ID() string {
+	if id := strings.ToLower(strings.TrimSpace(o.OSRelease["ID"])); id != "" {
+		return id
+	}
+
+	return "linux"
+}
+
+// VersionID returns the os-release `VERSION_ID` field, or an empty string if
+// unset.
+func (o osMetadata) VersionID() string {
+	return strings.TrimSpace(o.OSRelease["VERSION_ID"])
+}
+
+// Like returns the os-release `ID_LIKE` field parsed into a slice of
+// distribution IDs, or an empty slice if unset.
+func (o osMetadata) Like() []string {
+	like := strings.Fields(strings.ToLower(o.OSRelease["ID_LIKE"]))
+	if like == nil {
+		return []string{}
+	}
+
+	return like
+}
+
+// IsDistro returns true if the system's os-release `ID` or `ID_LIKE` fields
+// match the given distribution ID (ie, `IsDistro "debian"` is true on Ubuntu).
+func (o osMetadata) IsDistro(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	return o.ID() == name || slices.Contains(o.Like(), name)
+}
+
+// Family returns the normalized distribution family of the system (ie,
+// "debian", "rhel", "suse", "arch", "alpine"), based on the os-release `ID`
+// and `ID_LIKE` fields. If the distribution isn't a well known one, the last
+// (most generic) `ID_LIKE` entry is returned, falling back to the `ID`.
+func (o osMetadata) Family() string {
+	ids := append([]string{o.ID()}, o.Like()...)
+	for _, id := range ids {
+		if family, found := distroFamilies[id]; found {
+			return family
+		}
+	}
+
+	return ids[len(ids)-1]
+}
+
 func getOSMetadata(ctx context.Context, logger *slog.Logger) osMetadata {
 	// os metadata for templates
 	mdLogger := logger.With(
 		slog.String("metadata_collector", "os"),
 	)
 
-	osReleaseFile, err := os.Open(distro.Path)
+	// distro.Path is relative, for use with an fs.FS
+	osReleasePath := filepath.Join("/", distro.Path)
+	osReleaseFile, err := os.Open(osReleasePath)
 	if err != nil {
 		mdLogger.LogAttrs(
 			ctx,
 			slog.LevelError,
 			"Failed to open os-release file",
 			slog.String("err", err.Error()),
-			slog.String("path", distro.Path),
+			slog.String("path", osReleasePath),
 		)
 	}
 	osRelease, err := distro.Parse(ctx, osReleaseFile)
@@ -50,7 +157,7 @@ func getOSMetadata(ctx context.Context, logger *slog.Logger) osMetadata {
 			slog.LevelError,
 			"Failed to parse os-release file",
 			slog.String("err", err.Error()),
-			slog.String("path", distro.Path),
+			slog.String("path", osReleasePath),
 		)
 	}
 	osData := osMetadata{
@@ -112,14 +219,14 @@ func getCPUMetadata(ctx context.Context, logger *slog.Logger) cpuMetadata {
 		slog.String("metadata_collector", "cpu"),
 	)
 
-	fs, err := procfs.NewFS(procDir)
+	fs, err := procfs.NewFS(procDir())
 	if err != nil {
 		mdLogger.LogAttrs(
 			ctx,
 			slog.LevelError,
 			"Failed to create procfs for cpu metadata",
 			slog.String("err", err.Error()),
-			slog.String("path", procDir),
+			slog.String("path", procDir()),
 		)
 	}
 
@@ -145,14 +252,14 @@ func getMemoryMetadata(ctx context.Context, logger *slog.Logger) memoryMetadata
 		slog.String("metadata_collector", "memory"),
 	)
 
-	fs, err := procfs.NewFS(procDir)
+	fs, err := procfs.NewFS(procDir())
 	if err != nil {
 		mdLogger.LogAttrs(
 			ctx,
 			slog.LevelError,
 			"Failed to create procfs for memory metadata",
 			slog.String("err", err.Error()),
-			slog.String("path", procDir),
+			slog.String("path", procDir()),
 		)
 	}
 
@@ -171,30 +278,110 @@ func getMemoryMetadata(ctx context.Context, logger *slog.Logger) memoryMetadata
 
 // storage metadata
 
-const (
-	mountInfoFile = procDir + "/self/mountinfo"
-	blockDevDir   = sysDir + "/block"
-)
-
 type disk struct {
 	Name    string
 	Virtual bool
 	SSD     bool
 }
 
+// mountSpace is the space usage of a mounted filesystem, in bytes.
+type mountSpace struct {
+	Size      uint64 // total size of the filesystem
+	Free      uint64 // free space, including space reserved for root
+	Available uint64 // free space available to unprivileged users
+	Used      uint64
+}
+
+// AvailablePercent returns the percentage of the filesystem available to
+// unprivileged users (as reported by `df`), or 0 if the size is unknown.
+func (ms mountSpace) AvailablePercent() float64 {
+	if ms.Size == 0 {
+		return 0
+	}
+
+	return float64(ms.Available) / float64(ms.Size) * 100
+}
+
+// getMountSpace returns the space usage of the filesystem mounted at the path.
+func getMountSpace(path string) (mountSpace, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return mountSpace{}, err
+	}
+
+	bsize := uint64(st.Bsize)
+	return mountSpace{
+		Size:      st.Blocks * bsize,
+		Free:      st.Bfree * bsize,
+		Available: st.Bavail * bsize,
+		Used:      (st.Blocks - st.Bfree) * bsize,
+	}, nil
+}
+
+// mount is a mounted filesystem along with its space usage. The space usage is
+// zero for filesystems that couldn't be stat'd.
+type mount struct {
+	*procfs.MountInfo
+	mountSpace
+}
+
 type storageMetadata struct {
-	Mounts []*procfs.MountInfo
+	Mounts []mount
 	Disks  []disk
 }
 
+// MountFor returns the mount mounted at the given path, or nil if the path
+// isn't a mount point. If multiple filesystems are mounted at the path, the
+// last one (which is the one visible) is returned.
+func (s storageMetadata) MountFor(path string) *mount {
+	path = filepath.Clean(path)
+
+	var found *mount
+	for i, m := range s.Mounts {
+		if m.MountPoint == path {
+			found = &s.Mounts[i]
+		}
+	}
+
+	return found
+}
+
+// FSTypeFor returns the filesystem type (ie, "xfs" or "ext4") of the mount
+// containing the given path, which need not be a mount point itself, or an
+// empty string if no mount contains it.
+func (s storageMetadata) FSTypeFor(path string) string {
+	path = filepath.Clean(path)
+
+	var found *mount
+	for i, m := range s.Mounts {
+		rel, err := filepath.Rel(m.MountPoint, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+
+		// the longest matching mount point contains the path
+		if found == nil || len(m.MountPoint) >= len(found.MountPoint) {
+			found = &s.Mounts[i]
+		}
+	}
+
+	if found == nil {
+		return ""
+	}
+
+	return found.FSType
+}
+
 func getStorageMetadata(ctx context.Context, logger *slog.Logger) storageMetadata {
 	mdLogger := logger.With(
 		slog.String("metadata_collector", "storage"),
 	)
 
 	storageMD := storageMetadata{}
+	blockDevDir := filepath.Join(sysDir(), "block")
 
-	fs, err := blockdevice.NewFS(procDir, sysDir)
+	var blockDevs []string
+	fs, err := blockdevice.NewFS(procDir(), sysDir())
 	if err != nil {
 		mdLogger.LogAttrs(
 			ctx,
@@ -202,11 +389,8 @@ func getStorageMetadata(ctx context.Context, logger *slog.Logger) storageMetadat
 			"Failed to create blockdevice FS",
 			slog.String("err", err.Error()),
 		)
-	}
-
-	blockDevs, err := fs.SysBlockDevices()
-	if err != nil {
-		logger.LogAttrs(
+	} else if blockDevs, err = fs.SysBlockDevices(); err != nil {
+		mdLogger.LogAttrs(
 			ctx,
 			slog.LevelError,
 			"Failed to list block devices",
@@ -257,17 +441,113 @@ func getStorageMetadata(ctx context.Context, logger *slog.Logger) storageMetadat
 
 	storageMD.Disks = disks
 
-	mounts, err := procfs.GetMounts()
+	var mounts []*procfs.MountInfo
+	procFS, err := procfs.NewFS(procDir())
+	if err == nil {
+		var self procfs.Proc
+		if self, err = procFS.Self(); err == nil {
+			mounts, err = self.MountInfo()
+		}
+	}
 	if err != nil {
 		mdLogger.LogAttrs(
 			ctx,
 			slog.LevelError,
 			"Failed to get mounts",
 			slog.String("err", err.Error()),
-			slog.String("path", mountInfoFile),
+			slog.String("path", filepath.Join(procDir(), "self/mountinfo")),
 		)
 	}
-	storageMD.Mounts = mounts
+	for _, m := range mounts {
+		space, err := getMountSpace(m.MountPoint)
+		if err != nil {
+			// pseudo filesystems and mounts that aren't accessible
+			// (ie, in containers) are expected to fail
+			mdLogger.LogAttrs(
+				ctx,
+				slog.LevelDebug,
+				"Failed to get space usage for mount",
+				slog.String("err", err.Error()),
+				slog.String("path", m.MountPoint),
+			)
+		}
+
+		storageMD.Mounts = append(storageMD.Mounts, mount{MountInfo: m, mountSpace: space})
+	}
 
 	return storageMD
 }
+
+// hostname metadata
+
+// hostnameLookupTimeout bounds DNS lookups for the system's FQDN, so that
+// broken resolvers don't stall reloads.
+const hostnameLookupTimeout = 5 * time.Second
+
+type hostnameMetadata struct {
+	FQDN          string
+	ShortHostname string
+}
+
+func getHostnameMetadata(ctx context.Context, logger *slog.Logger) hostnameMetadata {
+	mdLogger := logger.With(
+		slog.String("metadata_collector", "hostname"),
+	)
+
+	hostname := utils.GetHostname()
+	fqdn, err := lookupFQDN(ctx, hostname)
+	if err != nil {
+		mdLogger.LogAttrs(
+			ctx,
+			slog.LevelDebug,
+			"Failed to resolve FQDN, falling back to OS hostname",
+			slog.String("err", err.Error()),
+			slog.String("hostname", hostname),
+		)
+		fqdn = hostname
+	}
+
+	short, _, _ := strings.Cut(fqdn, ".")
+
+	return hostnameMetadata{
+		FQDN:          fqdn,
+		ShortHostname: short,
+	}
+}
+
+// lookupFQDN resolves the fully qualified domain name of the hostname, first
+// via its canonical name, then via reverse DNS of its addresses.
+func lookupFQDN(ctx context.Context, hostname string) (string, error) {
+	if strings.Contains(hostname, ".") {
+		return hostname, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hostnameLookupTimeout)
+	defer cancel()
+
+	if cname, err := net.DefaultResolver.LookupCNAME(ctx, hostname); err == nil {
+		if cname = strings.TrimSuffix(cname, "."); strings.Contains(cname, ".") {
+			return cname, nil
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return "", fmt.Errorf("Failed to lookup addresses for hostname: %v", err)
+	}
+
+	for _, addr := range addrs {
+		names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			if name = strings.TrimSuffix(name, "."); strings.HasPrefix(name, hostname+".") {
+				return name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("No fully qualified name found for hostname")
+}
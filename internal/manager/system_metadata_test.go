@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOSMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		osRelease map[string]string
+		id        string
+		versionID string
+		like      []string
+		family    string
+		isDistro  []string
+		notDistro []string
+	}{
+		{
+			name: "ubuntu",
+			osRelease: map[string]string{
+				"NAME":       "Ubuntu",
+				"ID":         "ubuntu",
+				"ID_LIKE":    "debian",
+				"VERSION_ID": "24.04",
+			},
+			id:        "ubuntu",
+			versionID: "24.04",
+			like:      []string{"debian"},
+			family:    "debian",
+			isDistro:  []string{"ubuntu", "debian", " Debian "},
+			notDistro: []string{"rhel", "ubuntu-core"},
+		},
+		{
+			name: "rocky",
+			osRelease: map[string]string{
+				"NAME":       "Rocky Linux",
+				"ID":         "rocky",
+				"ID_LIKE":    "rhel centos fedora",
+				"VERSION_ID": "9.4",
+			},
+			id:        "rocky",
+			versionID: "9.4",
+			like:      []string{"rhel", "centos", "fedora"},
+			family:    "rhel",
+			isDistro:  []string{"rocky", "rhel", "fedora"},
+			notDistro: []string{"debian"},
+		},
+		{
+			name: "opensuse tumbleweed",
+			osRelease: map[string]string{
+				"ID":         "opensuse-tumbleweed",
+				"ID_LIKE":    "opensuse suse",
+				"VERSION_ID": "20240101",
+			},
+			id:        "opensuse-tumbleweed",
+			versionID: "20240101",
+			like:      []string{"opensuse", "suse"},
+			family:    "suse",
+			isDistro:  []string{"suse"},
+		},
+		{
+			name: "unknown derivative falls back to most generic ID_LIKE",
+			osRelease: map[string]string{
+				"ID":      "mydistro",
+				"ID_LIKE": "Something Generic",
+			},
+			id:        "mydistro",
+			like:      []string{"something", "generic"},
+			family:    "generic",
+			isDistro:  []string{"mydistro", "generic"},
+			notDistro: []string{"debian"},
+		},
+		{
+			name: "unknown distro without ID_LIKE",
+			osRelease: map[string]string{
+				"ID": " MyDistro ",
+			},
+			id:       "mydistro",
+			like:     []string{},
+			family:   "mydistro",
+			isDistro: []string{"mydistro"},
+		},
+		{
+			name:      "empty os-release defaults to linux",
+			osRelease: map[string]string{},
+			id:        "linux",
+			like:      []string{},
+			family:    "linux",
+			isDistro:  []string{"linux"},
+			notDistro: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := osMetadata{OSRelease: tt.osRelease}
+
+			if got := o.ID(); got != tt.id {
+				t.Errorf("ID() = %q, want %q", got, tt.id)
+			}
+			if got := o.VersionID(); got != tt.versionID {
+				t.Errorf("VersionID() = %q, want %q", got, tt.versionID)
+			}
+			if got := o.Like(); !slices.Equal(got, tt.like) || got == nil {
+				t.Errorf("Like() = %#v, want %#v", got, tt.like)
+			}
+			if got := o.Family(); got != tt.family {
+				t.Errorf("Family() = %q, want %q", got, tt.family)
+			}
+			for _, name := range tt.isDistro {
+				if !o.IsDistro(name) {
+					t.Errorf("IsDistro(%q) = false, want true", name)
+				}
+			}
+			for _, name := range tt.notDistro {
+				if o.IsDistro(name) {
+					t.Errorf("IsDistro(%q) = true, want false", name)
+				}
+			}
+		})
+	}
+}
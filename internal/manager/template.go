@@ -3,7 +3,9 @@ package manager
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"text/template"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/go-sprout/sprout/registry/uniqueid"
 	socktmpl "github.com/hashicorp/go-sockaddr/template"
 	"github.com/oklog/ulid/v2"
+	"github.com/spf13/viper"
 
 	"github.com/tjhop/mango/internal/shell"
 )
@@ -39,11 +42,14 @@ type templateView struct {
 
 type metadata struct {
 	ModuleName    string // name of the module/directive executing the template
+	ModuleState   string // desired state of the module (`present` or `absent`), empty for directives
 	Enrolled      bool
 	RunID         string
 	ManagerName   string
 	InventoryPath string
 	Hostname      string
+	FQDN          string // system's fully qualified domain name, falling back to the OS hostname
+	ShortHostname string // first label of the FQDN
 }
 
 type templateData struct {
@@ -58,6 +64,50 @@ type templateData struct {
 	Storage    storageMetadata
 }
 
+// templateError is returned for failures to parse or execute a template, so
+// that template failures can be distinguished from script failures.
+type templateError struct {
+	err error
+}
+
+func (e templateError) Error() string { return e.err.Error() }
+func (e templateError) Unwrap() error { return e.err }
+
+// abortOnTemplateError aborts the run in progress if err is a template error
+// and `manager.template-error-fatal` is enabled, so that a broken shared
+// template fails the whole run loudly rather than failing each module.
+func (mgr *Manager) abortOnTemplateError(ctx context.Context, logger *slog.Logger, err error) {
+	var te templateError
+	if !viper.GetBool("manager.template-error-fatal") || !errors.As(err, &te) || mgr.abortRun == nil {
+		return
+	}
+
+	logger.LogAttrs(
+		ctx,
+		slog.LevelError,
+		"Template error is fatal, aborting run",
+		slog.String("err", err.Error()),
+	)
+	mgr.abortRun(fmt.Errorf("Run aborted after template error: %v", err))
+}
+
+// varFuncs returns the template funcs backed by the merged variables of the
+// script being templated.
+func varFuncs(vars VariableMap) template.FuncMap {
+	return template.FuncMap{
+		// varOr returns the value of the variable if set, otherwise the
+		// fallback. Unlike indexing `.Mango.Vars`, missing variables never
+		// render as `<no value>`.
+		"varOr": func(key, fallback string) string {
+			if val, found := vars[key]; found {
+				return val
+			}
+
+			return fallback
+		},
+	}
+}
+
 func templateScript(ctx context.Context, path string, view templateView, funcMap template.FuncMap, invDefinedTemplates ...string) (string, error) {
 	var (
 		buf bytes.Buffer
@@ -83,47 +133,75 @@ func templateScript(ctx context.Context, path string, view templateView, funcMap
 		time.NewRegistry(),
 		uniqueid.NewRegistry(),
 	); err != nil {
-		return "", fmt.Errorf("Failed to add sprout registries to handler: %s\n", err.Error())
+		return "", templateError{fmt.Errorf("Failed to add sprout registries to handler: %s\n", err.Error())}
 	}
 
-	// init template and funcs
+	// init template and funcs. mango's own funcs are added last, so that
+	// they take precedence over library funcs of the same name (ie, `now`
+	// uses the manager's clock)
 	t := template.New(filepath.Base(path)).
-		Funcs(funcMap).
 		Funcs(socktmpl.SourceFuncs).
 		Funcs(socktmpl.SortFuncs).
 		Funcs(socktmpl.FilterFuncs).
 		Funcs(socktmpl.HelperFuncs).
-		Funcs(handler.Build())
+		Funcs(handler.Build()).
+		Funcs(funcMap).
+		Funcs(varFuncs(view.Mango.Vars))
+
+	// override sprout's random functions with deterministic ones, if requested
+	if seed := viper.GetString("manager.template-seed"); seed != "" {
+		t = t.Funcs(seededRandomFuncs(seed, view.Mango.Metadata))
+	}
 
 	if len(invDefinedTemplates) > 0 {
 		if t, err = t.ParseFiles(invDefinedTemplates...); err != nil {
-			return "", fmt.Errorf("Failed to parse common templates in %#v: %s", invDefinedTemplates, err)
+			return "", templateError{fmt.Errorf("Failed to parse common templates in %#v: %s", invDefinedTemplates, err)}
 		}
 	}
 
 	t, err = t.ParseFiles(path)
 	if err != nil {
-		return "", fmt.Errorf("Failed to parse template %s: %s", path, err)
+		return "", templateError{fmt.Errorf("Failed to parse template %s: %s", path, err)}
 	}
 
 	err = t.Execute(&buf, view)
 	if err != nil {
-		return "", fmt.Errorf("Failed to execute template for %s: %s", path, err)
+		return "", templateError{fmt.Errorf("Failed to execute template for %s: %s", path, err)}
 	}
 
 	return buf.String(), nil
 }
 
+// getRunMetadata returns the runtime metadata for templates from the
+// context. Any metadata missing from the context is left as the zero value, so
+// that templates can still be rendered outside of a full manager run.
+func getRunMetadata(ctx context.Context, name string) metadata {
+	md := metadata{ModuleName: name}
+
+	if runID, ok := ctx.Value(contextKeyRunID).(ulid.ULID); ok {
+		md.RunID = runID.String()
+	}
+	if enrolled, ok := ctx.Value(contextKeyEnrolled).(bool); ok {
+		md.Enrolled = enrolled
+	}
+	if managerName, ok := ctx.Value(contextKeyManagerName).(string); ok {
+		md.ManagerName = managerName
+	}
+	if inventoryPath, ok := ctx.Value(contextKeyInventoryPath).(string); ok {
+		md.InventoryPath = inventoryPath
+	}
+	if hostname, ok := ctx.Value(contextKeyHostname).(string); ok {
+		md.Hostname = hostname
+	}
+
+	return md
+}
+
 func (mgr *Manager) getTemplateData(ctx context.Context, name string, host, mod, all VariableMap) templateView {
 	// runtime metadata for templates
-	runtimeData := metadata{
-		ModuleName:    name,
-		RunID:         ctx.Value(contextKeyRunID).(ulid.ULID).String(),
-		Enrolled:      ctx.Value(contextKeyEnrolled).(bool),
-		ManagerName:   ctx.Value(contextKeyManagerName).(string),
-		InventoryPath: ctx.Value(contextKeyInventoryPath).(string),
-		Hostname:      ctx.Value(contextKeyHostname).(string),
-	}
+	runtimeData := getRunMetadata(ctx, name)
+	runtimeData.FQDN = mgr.hostnames.FQDN
+	runtimeData.ShortHostname = mgr.hostnames.ShortHostname
 
 	// assemble all template data
 	allTemplateData := templateData{
@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/mango/internal/shell"
+)
+
+// getModuleWorkDir renders the module's workdir file (if any) and returns the
+// working directory that the module's scripts should be run in. An empty
+// string is returned if the module doesn't set a working directory, in which
+// case scripts are run in an ephemeral directory specific to the run. The
+// directory must already exist, unless `manager.create-module-workdirs` is
+// set.
+func (mgr *Manager) getModuleWorkDir(ctx context.Context, mod Module, view templateView, userTemplateFiles []string) (string, error) {
+	if mod.m.WorkDir == "" {
+		return "", nil
+	}
+
+	rendered, err := templateScript(ctx, mod.m.WorkDir, view, mgr.funcMap, userTemplateFiles...)
+	if err != nil {
+		metricManagerTemplateRenderFailedTotal.With(prometheus.Labels{"module": mod.String(), "script": "workdir"}).Inc()
+		return "", fmt.Errorf("Failed to template module workdir: %w", err)
+	}
+
+	dir := strings.TrimSpace(rendered)
+	if dir == "" {
+		return "", nil
+	}
+
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("Module workdir must be an absolute path: %s", dir)
+	}
+
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err) && viper.GetBool("manager.create-module-workdirs"):
+		if err := os.MkdirAll(dir, shell.DirMode()); err != nil {
+			return "", fmt.Errorf("Failed to create module workdir: %v", err)
+		}
+	case err != nil:
+		return "", fmt.Errorf("Failed to check module workdir: %v", err)
+	case !info.IsDir():
+		return "", fmt.Errorf("Module workdir is not a directory: %s", dir)
+	}
+
+	return dir, nil
+}
+
+// cleanupRunWorkDir removes the ephemeral working directory used by scripts
+// during the given run, once the run has finished. If the run failed and
+// `manager.keep-failed-run-workdirs` is set, the directory is kept for
+// debugging. Script logs are kept separately in the log dir, and are not
+// affected.
+func cleanupRunWorkDir(ctx context.Context, logger *slog.Logger, runID ulid.ULID, success bool) {
+	dir := shell.RunWorkDir(runID)
+	if !success && viper.GetBool("manager.keep-failed-run-workdirs") {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"Run failed, keeping run working directory",
+			slog.String("path", dir),
+		)
+		return
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"Failed to remove run working directory",
+			slog.String("err", err.Error()),
+			slog.String("path", dir),
+		)
+		return
+	}
+
+	logger.LogAttrs(
+		ctx,
+		slog.LevelDebug,
+		"Removed run working directory",
+		slog.String("path", dir),
+	)
+}
@@ -0,0 +1,154 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// Credential contains the user/group that a script is run as, as set by a
+// module's `become` file. External commands are run as the user, and files
+// opened by the interpreter for redirections (and `source`) are opened as the
+// user. Other shell builtins are run by the interpreter within mango itself,
+// and are not affected (ie, `cd` and `test -f` check paths as mango's user).
+type Credential struct {
+	// Username is the name of the user to run as
+	Username string
+	// UID is the ID of the user to run as
+	UID uint32
+	// GID is the ID of the primary group to run as
+	GID uint32
+	// Groups are the IDs of the user's supplementary groups
+	Groups []uint32
+	// HomeDir is the user's home directory
+	HomeDir string
+}
+
+// ParseBecome parses a `user[:group]` spec into a credential. The user and
+// group may be given as names or numeric IDs, and must exist. If no group is
+// given, the user's primary group is used. Switching users requires mango to
+// be running as root, unless the spec is for mango's own user.
+func ParseBecome(spec string) (*Credential, error) {
+	spec = strings.TrimSpace(spec)
+	userSpec, groupSpec, _ := strings.Cut(spec, ":")
+	if userSpec == "" {
+		return nil, fmt.Errorf("No user provided in become spec '%s', expected `user[:group]` format", spec)
+	}
+
+	u, err := lookupUser(userSpec)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find become user '%s': %v", userSpec, err)
+	}
+
+	gid := u.Gid
+	if groupSpec != "" {
+		g, err := lookupGroup(groupSpec)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find become group '%s': %v", groupSpec, err)
+		}
+		gid = g.Gid
+	}
+
+	cred := &Credential{Username: u.Username, HomeDir: u.HomeDir}
+	if cred.UID, err = parseID(u.Uid); err != nil {
+		return nil, fmt.Errorf("Failed to parse UID for become user '%s': %v", userSpec, err)
+	}
+	if cred.GID, err = parseID(gid); err != nil {
+		return nil, fmt.Errorf("Failed to parse GID for become group '%s': %v", groupSpec, err)
+	}
+
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get groups for become user '%s': %v", userSpec, err)
+	}
+	for _, id := range groupIDs {
+		groupID, err := parseID(id)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse group ID for become user '%s': %v", userSpec, err)
+		}
+		cred.Groups = append(cred.Groups, groupID)
+	}
+
+	if euid := os.Geteuid(); euid != 0 && (int(cred.UID) != euid || int(cred.GID) != os.Getegid()) {
+		return nil, fmt.Errorf("Switching to become user '%s' requires mango to be running as root", spec)
+	}
+
+	return cred, nil
+}
+
+// ParseBecomeFile reads a module's `become` file and parses it into a
+// credential.
+func ParseBecomeFile(path string) (*Credential, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read become file: %v", err)
+	}
+
+	return ParseBecome(string(content))
+}
+
+// env returns the environment variables identifying the user, which are
+// provided to scripts run as the user.
+func (c *Credential) env() []string {
+	return []string{
+		"USER=" + c.Username,
+		"LOGNAME=" + c.Username,
+		"HOME=" + c.HomeDir,
+	}
+}
+
+// becomeOpenHandler returns an interpreter open handler that opens files as
+// the become user, so that redirections in a script run with `become` can't
+// read or write anything the user couldn't. The trusted paths are files
+// provided to the script by mango itself (ie, `MANGO_FACTS`), which are
+// opened as mango's own user.
+func becomeOpenHandler(cred *Credential, trustedPaths []string) interp.OpenHandlerFunc {
+	return func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+		hc := interp.HandlerCtx(ctx)
+		if path != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(hc.Dir, path)
+		}
+
+		// when not running as root, the become user is mango's own
+		// user, see ParseBecome
+		if os.Geteuid() != 0 || slices.Contains(trustedPaths, filepath.Clean(path)) {
+			return os.OpenFile(path, flag, perm)
+		}
+
+		f, err := openAsCredential(cred, path, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		return f, nil
+	}
+}
+
+func lookupUser(spec string) (*user.User, error) {
+	if _, err := strconv.ParseUint(spec, 10, 32); err == nil {
+		return user.LookupId(spec)
+	}
+
+	return user.Lookup(spec)
+}
+
+func lookupGroup(spec string) (*user.Group, error) {
+	if _, err := strconv.ParseUint(spec, 10, 32); err == nil {
+		return user.LookupGroupId(spec)
+	}
+
+	return user.LookupGroup(spec)
+}
+
+func parseID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	return uint32(n), err
+}
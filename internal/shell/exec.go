@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// execHandler returns an interpreter exec handler that runs external commands
+// the same way as `interp.DefaultExecHandler`, while additionally applying the
+// provided resource limits to each command before it runs.
+//
+// Each command is started in its own process group. When the context is
+// canceled, the whole process group is interrupted and then killed after
+// `killTimeout`, so that any children spawned by the command are not left
+// running after mango shuts down or a run is canceled.
+//
+// If a credential is provided, commands are run as that user/group.
+func execHandler(killTimeout time.Duration, limits Limits, cred *Credential) interp.ExecHandlerFunc {
+	return func(ctx context.Context, args []string) error {
+		hc := interp.HandlerCtx(ctx)
+		path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+		if err != nil {
+			fmt.Fprintln(hc.Stderr, err)
+			return interp.NewExitStatus(127)
+		}
+		cmd := exec.Cmd{
+			Path:   path,
+			Args:   args,
+			Env:    execEnv(hc.Env),
+			Dir:    hc.Dir,
+			Stdin:  hc.Stdin,
+			Stdout: hc.Stdout,
+			Stderr: hc.Stderr,
+		}
+
+		setProcessGroup(&cmd)
+		if err := setCredential(&cmd, cred); err != nil {
+			fmt.Fprintf(hc.Stderr, "Failed to run %s as become user: %v\n", args[0], err)
+			return interp.NewExitStatus(126)
+		}
+		// refuse to let the command run without the requested limits
+		// in place
+		if err := setLimits(&cmd, limits); err != nil {
+			fmt.Fprintf(hc.Stderr, "Failed to apply resource limits to %s: %v\n", args[0], err)
+			return interp.NewExitStatus(126)
+		}
+
+		err = cmd.Start()
+		if err == nil {
+			metricShellCommandsRunning.Inc()
+			defer metricShellCommandsRunning.Dec()
+			pid := cmd.Process.Pid
+
+			waitDone := make(chan struct{})
+			if done := ctx.Done(); done != nil {
+				go func() {
+					select {
+					case <-waitDone:
+						return
+					case <-done:
+					}
+
+					if killTimeout <= 0 {
+						_ = signalProcessGroup(pid, syscall.SIGKILL)
+						return
+					}
+
+					_ = signalProcessGroup(pid, syscall.SIGINT)
+					select {
+					case <-waitDone:
+					case <-time.After(killTimeout):
+						_ = signalProcessGroup(pid, syscall.SIGKILL)
+					}
+				}()
+			}
+
+			err = cmd.Wait()
+			close(waitDone)
+
+			if ctx.Err() != nil {
+				// the command may have exited on interrupt while
+				// leaving children behind (background jobs ignore
+				// SIGINT), so make sure nothing in the group outlives
+				// the cancellation
+				_ = signalProcessGroup(pid, syscall.SIGKILL)
+			}
+		}
+
+		switch err := err.(type) {
+		case *exec.ExitError:
+			// started, but errored - default to 1 if OS
+			// doesn't have exit statuses
+			if status, ok := err.Sys().(syscall.WaitStatus); ok {
+				if status.Signaled() {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					return interp.NewExitStatus(uint8(128 + status.Signal()))
+				}
+				return interp.NewExitStatus(uint8(status.ExitStatus()))
+			}
+			return interp.NewExitStatus(1)
+		case *exec.Error:
+			// did not start
+			fmt.Fprintf(hc.Stderr, "%v\n", err)
+			return interp.NewExitStatus(127)
+		default:
+			return err
+		}
+	}
+}
+
+// execEnv flattens the interpreter's environment into the exported
+// `key=value` pairs provided to external commands, mirroring the unexported
+// function of the same name in the interp package.
+func execEnv(env expand.Environ) []string {
+	list := make([]string, 0, 64)
+	env.Each(func(name string, vr expand.Variable) bool {
+		if !vr.IsSet() {
+			// If a variable is set globally but unset in the
+			// runner, ensure it's not part of the final list.
+			for i, kv := range list {
+				if strings.HasPrefix(kv, name+"=") {
+					list[i] = ""
+				}
+			}
+		}
+		if vr.Exported && vr.Kind == expand.String {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+	return list
+}
@@ -0,0 +1,149 @@
+package shell
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setCredential configures the command to be run as the provided user/group,
+// if any.
+func setCredential(cmd *exec.Cmd, cred *Credential) error {
+	if cred == nil {
+		return nil
+	}
+
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    cred.UID,
+		Gid:    cred.GID,
+		Groups: cred.Groups,
+	}
+
+	return nil
+}
+
+// limitsShimEnv is set in the environment of commands that are started via
+// the limits shim, containing the resource limits to apply as
+// `<cpu seconds>:<memory bytes>`.
+const limitsShimEnv = "_MANGO_EXEC_LIMITS"
+
+// When started as the limits shim, apply the limits and exec the command
+// before anything else runs. Doing this in init means that every binary
+// that runs scripts (mango, mh) acts as its own shim.
+func init() {
+	if spec, found := os.LookupEnv(limitsShimEnv); found {
+		runLimitsShim(spec, os.Args)
+	}
+}
+
+// setLimits configures the command to have the resource limits applied before
+// it runs, by starting it via the limits shim: a re-exec of the running
+// binary, which applies the limits to itself with setrlimit(2) and then execs
+// the command in place. Since the shim is replaced by the command, the
+// command keeps the process group, credentials, and pid of the started
+// process, and anything it forks inherits the limits.
+func setLimits(cmd *exec.Cmd, limits Limits) error {
+	if limits.CPUTime <= 0 && limits.Memory <= 0 {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("Failed to find executable for resource limits shim: %v", err)
+	}
+
+	cpuSecs := uint64(math.Ceil(limits.CPUTime.Seconds()))
+	// shim args: <shim> <command path> <command args...>
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d:%d", limitsShimEnv, cpuSecs, limits.Memory))
+
+	return nil
+}
+
+// runLimitsShim applies the encoded resource limits to the current process and
+// execs the command from the shim args in its place. It never returns.
+func runLimitsShim(spec string, args []string) {
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "Failed to apply resource limits: "+format+"\n", a...)
+		os.Exit(126)
+	}
+
+	var cpuSecs, memory uint64
+	if _, err := fmt.Sscanf(spec, "%d:%d", &cpuSecs, &memory); err != nil {
+		fail("invalid limits %q: %v", spec, err)
+	}
+	if len(args) < 3 {
+		fail("no command provided")
+	}
+
+	if cpuSecs > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpuSecs, Max: cpuSecs}); err != nil {
+			fail("failed to set CPU time limit: %v", err)
+		}
+	}
+	if memory > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: memory, Max: memory}); err != nil {
+			fail("failed to set memory limit: %v", err)
+		}
+	}
+
+	// don't leak the shim into the command's environment
+	os.Unsetenv(limitsShimEnv)
+	err := unix.Exec(args[1], args[2:], os.Environ())
+	fmt.Fprintf(os.Stderr, "%s: %v\n", args[2], err)
+	os.Exit(127)
+}
+
+// openAsCredential opens the file with the filesystem credentials of the
+// provided user/group, so that permission checks are made against the user
+// rather than mango's own user.
+//
+// Filesystem credentials are per thread, so the file is opened from a new
+// goroutine that is locked to its thread and never unlocked, which makes the
+// runtime discard the thread when the goroutine exits rather than reusing it
+// with the changed credentials.
+func openAsCredential(cred *Credential, path string, flag int, perm os.FileMode) (*os.File, error) {
+	type result struct {
+		f   *os.File
+		err error
+	}
+	resCh := make(chan result, 1)
+
+	go func() {
+		runtime.LockOSThread()
+
+		groups := make([]int, len(cred.Groups))
+		for i, g := range cred.Groups {
+			groups[i] = int(g)
+		}
+		if err := unix.Setgroups(groups); err != nil {
+			resCh <- result{err: &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("failed to set become groups: %w", err)}}
+			return
+		}
+
+		// setfsuid/setfsgid don't reliably report errors, so verify
+		// the change took effect by setting it again
+		_, _ = unix.SetfsgidRetGid(int(cred.GID))
+		if prev, _ := unix.SetfsgidRetGid(int(cred.GID)); prev != int(cred.GID) {
+			resCh <- result{err: &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("failed to set become group")}}
+			return
+		}
+		_, _ = unix.SetfsuidRetUid(int(cred.UID))
+		if prev, _ := unix.SetfsuidRetUid(int(cred.UID)); prev != int(cred.UID) {
+			resCh <- result{err: &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("failed to set become user")}}
+			return
+		}
+
+		f, err := os.OpenFile(path, flag, perm)
+		resCh <- result{f: f, err: err}
+	}()
+
+	res := <-resCh
+	return res.f, res.err
+}
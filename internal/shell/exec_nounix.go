@@ -0,0 +1,23 @@
+//go:build !unix
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on platforms without process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup falls back to signaling only the process itself on
+// platforms without process groups.
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return p.Signal(sig)
+}
@@ -0,0 +1,32 @@
+//go:build !linux
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// setCredential is only supported on Linux.
+func setCredential(cmd *exec.Cmd, cred *Credential) error {
+	if cred != nil {
+		return fmt.Errorf("Running scripts as another user is only supported on Linux")
+	}
+
+	return nil
+}
+
+// setLimits is only supported on Linux.
+func setLimits(cmd *exec.Cmd, limits Limits) error {
+	if limits.CPUTime > 0 || limits.Memory > 0 {
+		return fmt.Errorf("Resource limits are only supported on Linux")
+	}
+
+	return nil
+}
+
+// openAsCredential is only supported on Linux.
+func openAsCredential(cred *Credential, path string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("Running scripts as another user is only supported on Linux")}
+}
@@ -0,0 +1,108 @@
+//go:build unix
+
+package shell
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// processAlive reports whether the process exists and isn't a zombie waiting
+// to be reaped.
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		// no procfs to check for zombies, trust the signal
+		return true
+	}
+	// state is the field after the parenthesized command name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func TestExecHandlerCancelKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "pid")
+
+	// the background sleep is a child of the command, sharing its process
+	// group. It ignores SIGINT, so only the follow up SIGKILL stops it.
+	script := `sh -c 'sleep 60 & echo $! > ` + pidFile + `; wait'`
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		t.Fatalf("failed to parse script: %v", err)
+	}
+
+	runner, err := interp.New(
+		interp.StdIO(nil, io.Discard, io.Discard),
+		interp.Dir(dir),
+		interp.ExecHandlers(func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+			return execHandler(100*time.Millisecond, Limits{}, nil)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create runner: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- runner.Run(ctx, file) }()
+
+	var childPid int
+	deadline := time.Now().Add(5 * time.Second)
+	for childPid == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for child process to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		data, err := os.ReadFile(pidFile)
+		if err != nil || !strings.HasSuffix(string(data), "\n") {
+			continue
+		}
+		childPid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			t.Fatalf("failed to parse child pid: %v", err)
+		}
+	}
+	if !processAlive(childPid) {
+		t.Fatalf("child process %d not running before cancel", childPid)
+	}
+
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled from canceled run, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after context was canceled")
+	}
+
+	// the orphaned child may take a moment to be reaped
+	deadline = time.Now().Add(5 * time.Second)
+	for processAlive(childPid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(childPid, syscall.SIGKILL)
+			t.Fatalf("child process %d still running after context was canceled", childPid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
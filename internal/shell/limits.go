@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/tjhop/mango/pkg/utils"
+)
+
+// MinMemoryLimit is the smallest memory limit that can be set. The memory
+// limit caps each command's whole virtual address space, including its shared
+// libraries, so with much lower limits even trivial commands fail to start
+// (exiting 127 with an error loading shared libraries). Larger programs may
+// need considerably more.
+const MinMemoryLimit = 16 << 20
+
+// Limits contains optional resource limits that are applied to each external
+// command run by a script. Shell builtins are run by the interpreter within
+// mango itself and are not subject to these limits. A zero value for a limit
+// means unlimited.
+//
+// On Linux, limits are applied by starting each command via a re-exec of the
+// mango binary, which sets the limits on itself and then execs the command in
+// its place. If that fails, the command exits 126 (limits couldn't be applied)
+// or 127 (the command couldn't be executed), with the reason on stderr. Limits
+// are not supported on other platforms.
+type Limits struct {
+	// CPUTime is the maximum amount of CPU time each command may consume
+	CPUTime time.Duration
+	// Memory is the maximum size of each command's virtual address space, in bytes
+	Memory uint64
+}
+
+// Merge returns a copy of the limits, with any non-zero limits from `other`
+// taking precedence.
+func (l Limits) Merge(other Limits) Limits {
+	if other.CPUTime > 0 {
+		l.CPUTime = other.CPUTime
+	}
+	if other.Memory > 0 {
+		l.Memory = other.Memory
+	}
+
+	return l
+}
+
+// ParseLimits parses resource limits from a CPU time duration string (ie,
+// `30s`) and a memory size string (ie, `512MiB`). Empty strings are treated as
+// unlimited.
+func ParseLimits(cpu, memory string) (Limits, error) {
+	var (
+		limits Limits
+		err    error
+	)
+
+	if cpu = strings.TrimSpace(cpu); cpu != "" {
+		limits.CPUTime, err = time.ParseDuration(cpu)
+		if err != nil {
+			return Limits{}, fmt.Errorf("Failed to parse CPU time limit '%s': %v", cpu, err)
+		}
+	}
+
+	if memory = strings.TrimSpace(memory); memory != "" {
+		limits.Memory, err = humanize.ParseBytes(memory)
+		if err != nil {
+			return Limits{}, fmt.Errorf("Failed to parse memory limit '%s': %v", memory, err)
+		}
+		if err := ValidateMemoryLimit(limits.Memory); err != nil {
+			return Limits{}, err
+		}
+	}
+
+	return limits, nil
+}
+
+// ValidateMemoryLimit returns an error if the memory limit is set, but below
+// `MinMemoryLimit`.
+func ValidateMemoryLimit(memory uint64) error {
+	if memory > 0 && memory < MinMemoryLimit {
+		return fmt.Errorf("Memory limit %s is below the minimum of %s", humanize.IBytes(memory), humanize.IBytes(MinMemoryLimit))
+	}
+
+	return nil
+}
+
+// ParseLimitsFile parses resource limits from a file containing newline
+// delimited `key=value` pairs. Supported keys are `cpu` (CPU time, as a
+// duration like `30s`) and `memory` (address space size, like `512MiB`). The
+// first invalid line is returned as an error.
+func ParseLimitsFile(path string) (Limits, error) {
+	var (
+		cpu, memory string
+		err         error
+	)
+
+	// the whole file is always read, rather than returning on the first
+	// error, so that the line reader isn't left blocked
+	for line := range utils.ReadFileLines(path) {
+		if err != nil {
+			continue
+		}
+
+		if line.Err != nil {
+			err = line.Err
+			continue
+		}
+
+		text := strings.TrimSpace(line.Text)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(text, "=")
+		if !found {
+			err = fmt.Errorf("Invalid line '%s' in limits file, expected `key=value` format", text)
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "cpu":
+			cpu = value
+		case "memory":
+			memory = value
+		default:
+			err = fmt.Errorf("Unsupported key '%s' in limits file, must be one of: [cpu, memory]", key)
+		}
+	}
+
+	if err != nil {
+		return Limits{}, err
+	}
+
+	return ParseLimits(cpu, memory)
+}
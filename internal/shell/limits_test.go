@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseLimitsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Limits
+		wantErr bool
+	}{
+		{
+			name:    "cpu and memory",
+			content: "# comment\ncpu=30s\n\nmemory = 512MiB\n",
+			want:    Limits{CPUTime: 30 * time.Second, Memory: 512 << 20},
+		},
+		{
+			name:    "empty",
+			content: "",
+		},
+		{
+			name:    "invalid line",
+			content: "cpu\nmemory=512MiB\ncpu=30s\n",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported key",
+			content: "nproc=10\nmemory=512MiB\ncpu=30s\n",
+			wantErr: true,
+		},
+		{
+			name:    "memory below minimum",
+			content: "memory=1MiB\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			content: "cpu=soon\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "limits")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write limits file: %v", err)
+			}
+
+			before := runtime.NumGoroutine()
+			got, err := ParseLimitsFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimitsFile() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimitsFile() = %+v, want %+v", got, tt.want)
+			}
+
+			// the file's lines must be fully read, so that the
+			// line reader finishes rather than blocking forever
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > before {
+				if time.Now().After(deadline) {
+					t.Fatal("line reader still running after ParseLimitsFile() returned")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package shell
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// prometheus metrics, for the health of mango itself rather than the
+	// scripts it runs
+
+	metricShellScriptsRunning = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mango_shell_scripts_running",
+			Help: "Number of scripts currently being run by the shell interpreter",
+		},
+	)
+
+	metricShellCommandsRunning = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mango_shell_commands_running",
+			Help: "Number of external commands started by scripts that are currently running",
+		},
+	)
+
+	metricManagerScriptOutputTruncatedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mango_manager_script_output_truncated_total",
+			Help: "A count of the total number of script stdout/stderr log files that were truncated for exceeding `--manager.max-output-size`",
+		},
+	)
+
+	metricShellOpenLogFiles = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mango_shell_open_log_files",
+			Help: "Number of script log files (stdout, stderr, exit status) currently held open",
+		},
+	)
+)
@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// limitWriter is a writer that writes up to limit bytes to the underlying
+// writer, after which a truncation marker is written once and any further
+// output is discarded. Discarded writes still report success, so that a
+// script's output being truncated doesn't cause the script to fail.
+type limitWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	limit     uint64
+	written   uint64
+	truncated bool
+}
+
+// newLimitWriter returns a writer that truncates output to the underlying
+// writer after limit bytes, or the underlying writer itself if limit is 0.
+func newLimitWriter(w io.Writer, limit uint64) io.Writer {
+	if limit == 0 {
+		return w
+	}
+
+	return &limitWriter{w: w, limit: limit}
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.truncated {
+		return len(p), nil
+	}
+
+	remaining := lw.limit - lw.written
+	if uint64(len(p)) <= remaining {
+		n, err := lw.w.Write(p)
+		lw.written += uint64(n)
+		return n, err
+	}
+
+	n, err := lw.w.Write(p[:remaining])
+	lw.written += uint64(n)
+	if err != nil {
+		return n, err
+	}
+
+	lw.truncated = true
+	metricManagerScriptOutputTruncatedTotal.Inc()
+	// best effort, the output is being discarded regardless
+	_, _ = fmt.Fprintf(lw.w, "\n[mango: output truncated after %d bytes]\n", lw.limit)
+
+	return len(p), nil
+}
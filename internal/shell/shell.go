@@ -3,15 +3,19 @@ package shell
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/oklog/ulid/v2"
 	"github.com/spf13/viper"
 	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/interp"
 	"mvdan.cc/sh/v3/syntax"
+
+	"github.com/tjhop/mango/internal/config"
 )
 
 // VariableSlice is an alias for `[]string`, where each item is an environment
@@ -177,6 +181,35 @@ func MakeVariableMap(varSlice VariableSlice) VariableMap {
 	return varMap
 }
 
+// VariableSource contains the value of a variable, as well as the source
+// (generally the path of the variables file) that set it
+type VariableSource struct {
+	Value  string
+	Source string
+}
+
+// SourcedVariableMap is a `VariableMap` paired with the source (generally the
+// path of the variables file) that the variables were read from
+type SourcedVariableMap struct {
+	Source string
+	Vars   VariableMap
+}
+
+// MergeVariablesWithSource merges the provided variable maps the same way as
+// `MergeVariables` (later maps win), while tracking which source set the
+// winning value for each variable.
+func MergeVariablesWithSource(maps ...SourcedVariableMap) map[string]VariableSource {
+	vars := make(map[string]VariableSource)
+
+	for _, sourced := range maps {
+		for k, v := range sourced.Vars {
+			vars[k] = VariableSource{Value: v, Source: sourced.Source}
+		}
+	}
+
+	return vars
+}
+
 func MergeVariables(maps ...VariableMap) VariableSlice {
 	vars := make(VariableMap)
 
@@ -194,17 +227,232 @@ func MergeVariables(maps ...VariableMap) VariableSlice {
 	return varSlice
 }
 
-// Run is responsible for assembling an interpreter's execution environment
-// (setting environment variables, working directory, IO/output, etc) and
-// running the command
+const (
+	// ScriptPathModePrepend prepends `manager.script-path` to the inherited PATH
+	ScriptPathModePrepend = "prepend"
+	// ScriptPathModeOverride replaces the inherited PATH with `manager.script-path`
+	ScriptPathModeOverride = "override"
+
+	// KeepRenderedScriptsAlways writes every rendered script to the log dir
+	KeepRenderedScriptsAlways = "always"
+	// KeepRenderedScriptsOnFailure writes the rendered script to the log dir
+	// only if the script fails
+	KeepRenderedScriptsOnFailure = "on-failure"
+	// KeepRenderedScriptsNever never writes rendered scripts to the log dir
+	KeepRenderedScriptsNever = "never"
+)
+
+// scriptPath returns the PATH that scripts should be run with, based on the
+// configured `manager.script-path` and `manager.script-path-mode`, and the
+// PATH inherited by mango. If no script path is configured, the inherited PATH
+// is returned as is.
+func scriptPath(inherited string) string {
+	path := viper.GetString("manager.script-path")
+	if path == "" {
+		return inherited
+	}
+
+	if strings.ToLower(viper.GetString("manager.script-path-mode")) == ScriptPathModeOverride || inherited == "" {
+		return path
+	}
+
+	return path + string(os.PathListSeparator) + inherited
+}
+
+// scriptEnv assembles the environment for a script. Precedence for the PATH
+// (lowest to highest) is:
+//   - the PATH inherited by mango
+//   - the configured `manager.script-path`, prepended or overriding as per
+//     `manager.script-path-mode`
+//   - a PATH provided in the script's variables
+//   - a PATH set by the script itself at runtime
+//
+// Note that PATH is filtered out when sourcing variables files, so in practice
+// the configured script path is the effective default for all scripts.
+func scriptEnv(allVars []string) []string {
+	env := os.Environ()
+	if path := scriptPath(os.Getenv("PATH")); path != os.Getenv("PATH") {
+		env = append(env, "PATH="+path)
+	}
+
+	// later entries win, so variables take precedence over the environment
+	return append(env, allVars...)
+}
+
+// RunWorkDir returns the path to the ephemeral working directory that scripts
+// are run in for the given run, when a module doesn't set its own working
+// directory.
+func RunWorkDir(runID ulid.ULID) string {
+	return filepath.Join(viper.GetString("mango.temp-dir"), runID.String())
+}
+
+const (
+	// DefaultFileMode is the mode of files created for script runs, if
+	// `manager.file-mode` isn't set
+	DefaultFileMode os.FileMode = 0644
+	// DefaultDirMode is the mode of directories created for script runs, if
+	// `manager.dir-mode` isn't set
+	DefaultDirMode os.FileMode = 0750
+)
+
+// FileMode returns the configured mode for files created for script runs (ie,
+// log files). Like all created files, the mode is subject to the umask.
+func FileMode() os.FileMode {
+	mode, _ := config.GetFileMode("manager.file-mode") // validated at startup
+	if mode == 0 {
+		return DefaultFileMode
+	}
+
+	return mode
+}
+
+// DirMode returns the configured mode for directories created for script runs
+// (ie, log and working directories). Like all created directories, the mode is
+// subject to the umask.
+func DirMode() os.FileMode {
+	mode, _ := config.GetFileMode("manager.dir-mode") // validated at startup
+	if mode == 0 {
+		return DefaultDirMode
+	}
+
+	return mode
+}
+
+// sensitiveFileMode returns the mode for script log files that frequently
+// contain sensitive rendered content (ie, secrets from variables): stdout,
+// stderr, and the rendered script. These are restricted to the owner, or the
+// owner and `manager.log-group` if set, regardless of `manager.file-mode`.
+func sensitiveFileMode(groupReadable bool) os.FileMode {
+	if groupReadable {
+		return FileMode() & 0640
+	}
+
+	return FileMode() & 0600
+}
+
+// logGroupID returns the GID of the group configured with `manager.log-group`,
+// or -1 if unset.
+func logGroupID() (int, error) {
+	spec := strings.TrimSpace(viper.GetString("manager.log-group"))
+	if spec == "" {
+		return -1, nil
+	}
+
+	g, err := lookupGroup(spec)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to lookup log group %s: %v", spec, err)
+	}
+
+	gid, err := parseID(g.Gid)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to parse log group ID %s: %v", g.Gid, err)
+	}
+
+	return int(gid), nil
+}
+
+// openSensitiveLog opens the script log file for appending, creating it with
+// the restricted mode and assigning it to the log group, if configured.
+func openSensitiveLog(path string, gid int) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, sensitiveFileMode(gid >= 0))
+	if err != nil {
+		return nil, err
+	}
+
+	if gid >= 0 {
+		if err := f.Chown(-1, gid); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// writeSensitiveLog writes the content to the script log file, the same as
+// `openSensitiveLog`.
+func writeSensitiveLog(path, content string, gid int) error {
+	f, err := openSensitiveLog(path, gid)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// RunOptions contains the settings for a script run. The zero value runs the
+// script with no variables or limits, as the current user, in an ephemeral
+// directory specific to the run, with no stdin.
+//   - Vars: a slice of strings in `key=value` pair containing the merged
+//     variables to be provided to the script as environment variables
+//   - Limits: resource limits to apply to external commands run by the script
+//   - WorkDir: the working directory to run the script in, or an empty string
+//     to use an ephemeral directory specific to this run (or the become user's
+//     home directory, if a credential is provided)
+//   - Become: an optional credential for the user/group to run the script's
+//     external commands as, or nil
+//   - Stdin: an optional reader to provide as the script's stdin, or nil
+//   - StderrCapture: an optional writer that the script's stderr is copied to
+//     in addition to the stderr log file (ie, a TailBuffer), or nil
+//   - Stdout: an optional writer that the script's stdout is streamed to in
+//     addition to the stdout log file (ie, `os.Stdout` for interactive runs), or nil
+//   - Stderr: an optional writer that the script's stderr is streamed to in
+//     addition to the stderr log file, or nil
+//   - MaxOutputSize: the maximum size in bytes of each of the script's stdout
+//     and stderr log files, after which output to the log file is truncated,
+//     or 0 for unlimited
+//   - Store: an optional key-value store the script can access with the
+//     `mango-get`/`mango-set` commands, or nil
+//   - TrustedPaths: absolute paths of files provided to the script by mango
+//     (ie, `MANGO_FACTS`), that redirections open as mango's own user even
+//     when the script is run as a become user
+type RunOptions struct {
+	Vars          []string
+	Limits        Limits
+	WorkDir       string
+	Become        *Credential
+	Stdin         io.Reader
+	StderrCapture io.Writer
+	Stdout        io.Writer
+	Stderr        io.Writer
+	MaxOutputSize uint64
+	Store         Store
+	TrustedPaths  []string
+}
+
+// outputWriters returns the non-nil writers from the given writers.
+func outputWriters(writers ...io.Writer) []io.Writer {
+	var ws []io.Writer
+	for _, w := range writers {
+		if w != nil {
+			ws = append(ws, w)
+		}
+	}
+
+	return ws
+}
+
+// Run is a wrapper around RunWithOptions, to run a script with only the given
+// variables set and otherwise default options.
+func Run(ctx context.Context, runID ulid.ULID, path, content string, allVars []string) (uint8, error) {
+	return RunWithOptions(ctx, runID, path, content, RunOptions{Vars: allVars})
+}
+
+// RunWithOptions is responsible for assembling an interpreter's execution
+// environment (setting environment variables, working directory, IO/output,
+// etc) and running the command
 // Accepts:
 //   - context
 //   - ULID specific to this run
 //   - path to the script
 //   - string containing the contents of the templated script
-//   - a slice of strings in `key=value` pair containing the merged variables to
-//     be provided to the script as environment variables
-func Run(ctx context.Context, runID ulid.ULID, path, content string, allVars []string) (uint8, error) {
+//   - options for the run, see `RunOptions`
+func RunWithOptions(ctx context.Context, runID ulid.ULID, path, content string, opts RunOptions) (uint8, error) {
 	if content == "" {
 		return 1, fmt.Errorf("No script data provided")
 	}
@@ -215,48 +463,107 @@ func Run(ctx context.Context, runID ulid.ULID, path, content string, allVars []s
 	// example path (started with `inventory.path`: './test/mockup/inventory'):
 	//	/var/log/mango/manager/run/01GZF2QSPGTCKHFSECPBQ6H8FQ/test/mockup/inventory/modules/test-env-vars/apply/stdout
 	logDir := filepath.Join(viper.GetString("mango.log-dir"), "manager/run", runID.String(), path)
-	if err := os.MkdirAll(logDir, 0750); err != nil && !os.IsExist(err) {
+	if err := os.MkdirAll(logDir, DirMode()); err != nil && !os.IsExist(err) {
 		return 1, fmt.Errorf("Failed to create directory for script logs: %v", err)
 	}
 
+	// script output and the rendered script itself frequently contain
+	// sensitive content, so they're restricted beyond `manager.file-mode`
+	logGID, err := logGroupID()
+	if err != nil {
+		return 1, err
+	}
+
 	// log stdout from script
-	stdoutLog, err := os.OpenFile(filepath.Join(logDir, "stdout"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	stdoutLog, err := openSensitiveLog(filepath.Join(logDir, "stdout"), logGID)
 	if err != nil {
 		return 1, fmt.Errorf("Failed to open script log for stdout: %v", err)
 	}
+	metricShellOpenLogFiles.Inc()
+	defer metricShellOpenLogFiles.Dec()
 	defer stdoutLog.Close()
 
 	// log stderr from script
-	stderrLog, err := os.OpenFile(filepath.Join(logDir, "stderr"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	stderrLog, err := openSensitiveLog(filepath.Join(logDir, "stderr"), logGID)
 	if err != nil {
 		return 1, fmt.Errorf("Failed to open script log for stderr: %v", err)
 	}
+	metricShellOpenLogFiles.Inc()
+	defer metricShellOpenLogFiles.Dec()
 	defer stderrLog.Close()
 
 	// log exit status from script
-	exitStatusLog, err := os.OpenFile(filepath.Join(logDir, "exit_status"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	exitStatusLog, err := os.OpenFile(filepath.Join(logDir, "exit_status"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, FileMode())
 	if err != nil {
 		return 1, fmt.Errorf("Failed to open script log for exit status: %v", err)
 	}
+	metricShellOpenLogFiles.Inc()
+	defer metricShellOpenLogFiles.Dec()
 	defer exitStatusLog.Close()
 
-	// log script content itself for testing template rendering
-	if err := os.WriteFile(filepath.Join(logDir, "script.mango-rendered"), []byte(content), 0644); err != nil {
-		return 1, fmt.Errorf("Failed to write rendered script to log file: %v", err)
+	// log script content itself for testing template rendering, unless
+	// disabled (ie, for scripts that render secrets)
+	renderedLog := filepath.Join(logDir, "script.mango-rendered")
+	succeeded := false
+	switch strings.ToLower(viper.GetString("manager.keep-rendered-scripts")) {
+	case KeepRenderedScriptsNever:
+	case KeepRenderedScriptsOnFailure:
+		defer func() {
+			if !succeeded {
+				// best effort, the script's failure is what gets reported
+				_ = writeSensitiveLog(renderedLog, content, logGID)
+			}
+		}()
+	default:
+		if err := writeSensitiveLog(renderedLog, content, logGID); err != nil {
+			return 1, fmt.Errorf("Failed to write rendered script to log file: %v", err)
+		}
 	}
 
-	// runtime dir prep
-	workDir := filepath.Join(viper.GetString("mango.temp-dir"), runID.String())
-	if err := os.MkdirAll(workDir, 0750); err != nil && !os.IsExist(err) {
-		return 1, fmt.Errorf("Failed to create working directory for script: %v", err)
+	// runtime dir prep. The ephemeral run directory is private to mango, so
+	// scripts run as another user default to the user's home directory.
+	if opts.WorkDir == "" && opts.Become != nil {
+		opts.WorkDir = "/"
+		if info, err := os.Stat(opts.Become.HomeDir); err == nil && info.IsDir() {
+			opts.WorkDir = opts.Become.HomeDir
+		}
+	}
+	if opts.WorkDir == "" {
+		opts.WorkDir = RunWorkDir(runID)
+		if err := os.MkdirAll(opts.WorkDir, DirMode()); err != nil && !os.IsExist(err) {
+			return 1, fmt.Errorf("Failed to create working directory for script: %v", err)
+		}
 	}
 
-	// create shell interpreter
-	runner, err := interp.New(
-		interp.Env(expand.ListEnviron(append(os.Environ(), allVars...)...)),
-		interp.StdIO(nil, stdoutLog, stderrLog),
-		interp.Dir(workDir),
-	)
+	if opts.Become != nil {
+		// variables still take precedence over the user's environment
+		opts.Vars = append(opts.Become.env(), opts.Vars...)
+	}
+
+	stdout := io.MultiWriter(outputWriters(newLimitWriter(stdoutLog, opts.MaxOutputSize), opts.Stdout)...)
+	stderr := io.MultiWriter(outputWriters(newLimitWriter(stderrLog, opts.MaxOutputSize), opts.StderrCapture, opts.Stderr)...)
+
+	// create shell interpreter. Store commands are handled before external
+	// commands, so that they're run within mango itself.
+	execHandlers := []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc{}
+	if opts.Store != nil {
+		execHandlers = append(execHandlers, storeHandler(opts.Store))
+	}
+	execHandlers = append(execHandlers, func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return execHandler(2*time.Second, opts.Limits, opts.Become)
+	})
+	runnerOpts := []interp.RunnerOption{
+		interp.Env(expand.ListEnviron(scriptEnv(opts.Vars)...)),
+		interp.StdIO(opts.Stdin, stdout, stderr),
+		interp.Dir(opts.WorkDir),
+		interp.ExecHandlers(execHandlers...),
+	}
+	if opts.Become != nil {
+		// files opened by the interpreter itself for redirections
+		// would otherwise be opened as mango's user
+		runnerOpts = append(runnerOpts, interp.OpenHandler(becomeOpenHandler(opts.Become, opts.TrustedPaths)))
+	}
+	runner, err := interp.New(runnerOpts...)
 	if err != nil {
 		return 1, fmt.Errorf("Failed to create shell interpreter: %s", err)
 	}
@@ -269,7 +576,9 @@ func Run(ctx context.Context, runID ulid.ULID, path, content string, allVars []s
 
 	// run it!
 	var exitStatus uint8
+	metricShellScriptsRunning.Inc()
 	err = runner.Run(ctx, file)
+	metricShellScriptsRunning.Dec()
 	if err != nil {
 		status, ok := interp.IsExitStatus(err)
 		if !ok {
@@ -279,6 +588,7 @@ func Run(ctx context.Context, runID ulid.ULID, path, content string, allVars []s
 
 		exitStatus = status
 	}
+	succeeded = exitStatus == 0
 
 	if _, err := exitStatusLog.WriteString(fmt.Sprintf("%d\n", exitStatus)); err != nil {
 		return 1, fmt.Errorf("Failed to write exit status log for status code '%d': %v", exitStatus, err)
@@ -0,0 +1,201 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/spf13/viper"
+)
+
+// setupRunDirs points mango's log and temp dirs at temporary directories for
+// the test, returning the log dir.
+func setupRunDirs(t *testing.T) string {
+	t.Helper()
+	t.Cleanup(viper.Reset)
+
+	logDir, tempDir := t.TempDir(), t.TempDir()
+	viper.Set("mango.log-dir", logDir)
+	viper.Set("mango.temp-dir", tempDir)
+
+	return logDir
+}
+
+func TestScriptPath(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	tests := []struct {
+		name       string
+		scriptPath string
+		mode       string
+		inherited  string
+		want       string
+	}{
+		{
+			name:      "unset defaults to inherited",
+			inherited: "/usr/bin" + sep + "/bin",
+			want:      "/usr/bin" + sep + "/bin",
+		},
+		{
+			name:       "mode defaults to prepend",
+			scriptPath: "/opt/bin",
+			inherited:  "/usr/bin",
+			want:       "/opt/bin" + sep + "/usr/bin",
+		},
+		{
+			name:       "prepend",
+			scriptPath: "/opt/bin",
+			mode:       ScriptPathModePrepend,
+			inherited:  "/usr/bin",
+			want:       "/opt/bin" + sep + "/usr/bin",
+		},
+		{
+			name:       "override",
+			scriptPath: "/opt/bin",
+			mode:       "Override",
+			inherited:  "/usr/bin",
+			want:       "/opt/bin",
+		},
+		{
+			name:       "prepend without inherited PATH",
+			scriptPath: "/opt/bin",
+			mode:       ScriptPathModePrepend,
+			want:       "/opt/bin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			viper.Set("manager.script-path", tt.scriptPath)
+			viper.Set("manager.script-path-mode", tt.mode)
+
+			if got := scriptPath(tt.inherited); got != tt.want {
+				t.Errorf("scriptPath(%q) = %q, want %q", tt.inherited, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptEnv(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("MANGO_TEST_INHERITED", "inherited")
+
+	tests := []struct {
+		name       string
+		scriptPath string
+		vars       []string
+		want       map[string]string
+	}{
+		{
+			name: "defaults to inherited environment",
+			want: map[string]string{
+				"PATH":                 "/usr/bin",
+				"MANGO_TEST_INHERITED": "inherited",
+			},
+		},
+		{
+			name:       "script path is the default PATH",
+			scriptPath: "/opt/bin",
+			want: map[string]string{
+				"PATH": "/opt/bin" + string(os.PathListSeparator) + "/usr/bin",
+			},
+		},
+		{
+			name:       "variables override defaults",
+			scriptPath: "/opt/bin",
+			vars:       []string{"PATH=/custom/bin", "MANGO_TEST_INHERITED=var"},
+			want: map[string]string{
+				"PATH":                 "/custom/bin",
+				"MANGO_TEST_INHERITED": "var",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			viper.Set("manager.script-path", tt.scriptPath)
+
+			// later entries win, the same as the interpreter's
+			// environment
+			env := MakeVariableMap(scriptEnv(tt.vars))
+			for k, want := range tt.want {
+				if got := env[k]; got != want {
+					t.Errorf("scriptEnv(%v)[%s] = %q, want %q", tt.vars, k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWithOptionsDefaults(t *testing.T) {
+	workDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		opts    RunOptions
+		wantDir func(runID ulid.ULID) string
+	}{
+		{
+			name:    "work dir defaults to the run's ephemeral dir",
+			wantDir: RunWorkDir,
+		},
+		{
+			name:    "work dir override",
+			opts:    RunOptions{WorkDir: workDir},
+			wantDir: func(ulid.ULID) string { return workDir },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRunDirs(t)
+			runID := ulid.Make()
+
+			var stdout bytes.Buffer
+			tt.opts.Stdout = &stdout
+			tt.opts.Vars = []string{"MANGO_TEST_VAR=set"}
+			rc, err := RunWithOptions(context.Background(), runID, "test/apply", `pwd; echo "$MANGO_TEST_VAR"`, tt.opts)
+			if err != nil || rc != 0 {
+				t.Fatalf("RunWithOptions() = %d, %v, want 0, nil", rc, err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+			want := []string{tt.wantDir(runID), "set"}
+			if !slices.Equal(lines, want) {
+				t.Errorf("script output = %q, want %q", lines, want)
+			}
+		})
+	}
+}
+
+func TestRunDefaults(t *testing.T) {
+	logDir := setupRunDirs(t)
+	runID := ulid.Make()
+
+	if _, err := RunWithOptions(context.Background(), runID, "test/apply", "", RunOptions{}); err == nil {
+		t.Error("expected error running empty script, got nil")
+	}
+
+	rc, err := Run(context.Background(), runID, "test/apply", "echo $MANGO_TEST_VAR; exit 3", []string{"MANGO_TEST_VAR=set"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if rc != 3 {
+		t.Errorf("Run() exit status = %d, want 3", rc)
+	}
+
+	// output is logged to the run's log dir for the script
+	stdout, err := os.ReadFile(filepath.Join(logDir, "manager/run", runID.String(), "test/apply", "stdout"))
+	if err != nil {
+		t.Fatalf("failed to read script stdout log: %v", err)
+	}
+	if got := strings.TrimSpace(string(stdout)); got != "set" {
+		t.Errorf("script stdout = %q, want %q", got, "set")
+	}
+}
@@ -0,0 +1,63 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// Store is a key-value store that scripts can read and write with the
+// `mango-get` and `mango-set` commands, to share values with other scripts.
+type Store interface {
+	Get(key string) (string, bool)
+	Set(key, value string)
+}
+
+// Commands provided to scripts for accessing the store.
+const (
+	// StoreGetCommand prints the value of the key in the store, exiting
+	// non-zero if the key isn't set: `mango-get KEY`
+	StoreGetCommand = "mango-get"
+	// StoreSetCommand sets the key in the store to the value:
+	// `mango-set KEY VALUE`
+	StoreSetCommand = "mango-set"
+)
+
+// storeHandler returns exec handler middleware that runs the store commands
+// within mango itself, passing all other commands through to the next
+// handler.
+func storeHandler(store Store) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			hc := interp.HandlerCtx(ctx)
+
+			switch args[0] {
+			case StoreGetCommand:
+				if len(args) != 2 {
+					fmt.Fprintf(hc.Stderr, "usage: %s KEY\n", StoreGetCommand)
+					return interp.NewExitStatus(2)
+				}
+
+				val, found := store.Get(args[1])
+				if !found {
+					return interp.NewExitStatus(1)
+				}
+				fmt.Fprintln(hc.Stdout, val)
+
+				return nil
+			case StoreSetCommand:
+				if len(args) != 3 {
+					fmt.Fprintf(hc.Stderr, "usage: %s KEY VALUE\n", StoreSetCommand)
+					return interp.NewExitStatus(2)
+				}
+
+				store.Set(args[1], args[2])
+
+				return nil
+			default:
+				return next(ctx, args)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package shell
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxTailLineLength is the maximum length of a single line retained by a
+// TailBuffer, to bound memory usage for scripts writing very long lines.
+const maxTailLineLength = 1024
+
+// TailBuffer is an io.Writer that retains only the last N lines written to
+// it. Lines longer than 1KiB are truncated.
+type TailBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	max     int
+	partial []byte
+}
+
+// NewTailBuffer returns a TailBuffer that retains the last n lines written.
+func NewTailBuffer(n int) *TailBuffer {
+	return &TailBuffer{max: n}
+}
+
+// Write implements io.Writer.
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, b := range p {
+		if b == '\n' {
+			t.addLine(string(t.partial))
+			t.partial = t.partial[:0]
+			continue
+		}
+
+		if len(t.partial) < maxTailLineLength {
+			t.partial = append(t.partial, b)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (t *TailBuffer) addLine(line string) {
+	if t.max <= 0 {
+		return
+	}
+
+	if len(t.lines) >= t.max {
+		t.lines = t.lines[1:]
+	}
+	t.lines = append(t.lines, line)
+}
+
+// Lines returns the retained lines, including any trailing line that has not
+// yet been terminated with a newline.
+func (t *TailBuffer) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([]string, len(t.lines), len(t.lines)+1)
+	copy(lines, t.lines)
+	if len(t.partial) > 0 && t.max > 0 {
+		lines = append(lines, string(t.partial))
+		if len(lines) > t.max {
+			lines = lines[1:]
+		}
+	}
+
+	return lines
+}
+
+// String returns the retained lines joined by newlines.
+func (t *TailBuffer) String() string {
+	return strings.Join(t.Lines(), "\n")
+}
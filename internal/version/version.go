@@ -1,6 +1,7 @@
 package version
 
 import (
+	"encoding/json"
 	"fmt"
 	"runtime"
 )
@@ -22,3 +23,27 @@ func Print(programName string) string {
 		runtime.Version(),
 	)
 }
+
+// Info contains build info about the binary, for machine readable output
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// PrintJSON outputs build info about the binary as JSON, for use with
+// automation that needs to check which version is deployed
+func PrintJSON() (string, error) {
+	info, err := json.Marshal(Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal version info as JSON: %v", err)
+	}
+
+	return string(info) + "\n", nil
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// HostnameSourceSystem uses the system's hostname
+	HostnameSourceSystem = "system"
+	// HostnameSourceFQDN uses the system's fully qualified domain name, as
+	// resolved via DNS
+	HostnameSourceFQDN = "fqdn"
+	// HostnameSourceFile reads the hostname from a file
+	HostnameSourceFile = "file"
+	// HostnameSourceCommand uses the output of a command as the hostname
+	HostnameSourceCommand = "command"
+
+	hostnameCommandTimeout = 30 * time.Second
+)
+
+// ResolveHostname returns the hostname from the given source. For the `file`
+// source, arg is the path of the file to read the hostname from, and for the
+// `command` source, arg is a command to run with `sh -c` that prints the
+// hostname. Leading/trailing whitespace is trimmed from the result.
+func ResolveHostname(source, arg string) (string, error) {
+	var (
+		hostname string
+		err      error
+	)
+
+	switch source {
+	case "", HostnameSourceSystem:
+		hostname, err = os.Hostname()
+	case HostnameSourceFQDN:
+		hostname, err = lookupFQDN()
+	case HostnameSourceFile:
+		if arg == "" {
+			return "", fmt.Errorf("No file provided for hostname source '%s'", source)
+		}
+
+		var data []byte
+		data, err = os.ReadFile(arg)
+		hostname = string(data)
+	case HostnameSourceCommand:
+		if arg == "" {
+			return "", fmt.Errorf("No command provided for hostname source '%s'", source)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), hostnameCommandTimeout)
+		defer cancel()
+
+		var out []byte
+		out, err = exec.CommandContext(ctx, "sh", "-c", arg).Output()
+		hostname = string(out)
+	default:
+		return "", fmt.Errorf("Unsupported hostname source '%s'", source)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to get hostname from source '%s': %v", source, err)
+	}
+
+	hostname = strings.TrimSpace(hostname)
+	if hostname == "" {
+		return "", fmt.Errorf("Empty hostname returned from source '%s'", source)
+	}
+
+	return hostname, nil
+}
+
+// lookupFQDN resolves the system's fully qualified domain name via DNS, first
+// trying the canonical name for the system hostname and then falling back to
+// a reverse lookup of the hostname's addresses.
+func lookupFQDN() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	if cname, err := net.LookupCNAME(hostname); err == nil {
+		if fqdn := strings.TrimSuffix(cname, "."); strings.Contains(fqdn, ".") {
+			return fqdn, nil
+		}
+	}
+
+	addrs, err := net.LookupHost(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	for _, addr := range addrs {
+		names, err := net.LookupAddr(addr)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			if fqdn := strings.TrimSuffix(name, "."); strings.Contains(fqdn, ".") {
+				return fqdn, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("No fully qualified domain name found for hostname '%s'", hostname)
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// prometheus metrics
+	metricFileLineReadersActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mango_file_line_readers_active",
+			Help: "Number of goroutines currently reading files line by line, which stay running until their lines are fully consumed",
+		},
+	)
+)
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// UserInfo contains information about the user/group that the current process
+// is running as.
+type UserInfo struct {
+	UID       string
+	GID       string
+	Username  string
+	Groupname string
+}
+
+// GetCurrentUserInfo returns information about the user/group the current
+// process is running as. If the user or group names can't be resolved (ie, in
+// minimal containers or NSS limited environments), the numeric UID/GID is used
+// in place of the name, and an error describing the failed lookup is returned
+// alongside the info so that callers can decide how to handle it.
+func GetCurrentUserInfo() (UserInfo, error) {
+	info := UserInfo{
+		UID: strconv.Itoa(os.Getuid()),
+		GID: strconv.Itoa(os.Getgid()),
+	}
+	info.Username = info.UID
+	info.Groupname = info.GID
+
+	var lookupErr error
+	u, err := user.LookupId(info.UID)
+	if err != nil {
+		lookupErr = fmt.Errorf("Failed to look up user for uid %s, using numeric uid: %v", info.UID, err)
+	} else {
+		info.Username = u.Username
+	}
+
+	g, err := user.LookupGroupId(info.GID)
+	if err != nil {
+		lookupErr = errors.Join(lookupErr, fmt.Errorf("Failed to look up group for gid %s, using numeric gid: %v", info.GID, err))
+	} else {
+		info.Groupname = g.Name
+	}
+
+	return info, lookupErr
+}
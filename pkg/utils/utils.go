@@ -3,6 +3,7 @@ package utils
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"os"
@@ -27,6 +28,18 @@ func GetFilesInDirectory(path string) ([]fs.DirEntry, error) {
 	return files, nil
 }
 
+// GetFilesInDirectoryFS is the same as GetFilesInDirectory, but reads the
+// directory at the provided path within the provided filesystem (ie, an
+// inventory's filesystem), rather than the OS filesystem.
+func GetFilesInDirectoryFS(fsys fs.FS, path string) ([]fs.DirEntry, error) {
+	files, err := fs.ReadDir(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read files in directory '%s': %v", path, err)
+	}
+
+	return files, nil
+}
+
 // FileLine contains fields corresponding to a single line entry
 // as received by `bufio.Scanner`. If Err is set, that means that a
 // non EOF error was received, indicating a file read failure of some
@@ -43,17 +56,34 @@ type FileLine struct {
 // and immediately send it to the channel for the consumer. Because the
 // channel is unbuffered, consumers will block while waiting.
 func ReadFileLines(path string) chan FileLine {
+	return readFileLines(path, func() (io.ReadCloser, error) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to retrieve absolute path for '%s': %v", path, err)
+		}
+
+		return os.Open(absPath)
+	})
+}
+
+// ReadFileLinesFS is the same as ReadFileLines, but reads the file at the
+// provided path within the provided filesystem (ie, an inventory's
+// filesystem), rather than the OS filesystem.
+func ReadFileLinesFS(fsys fs.FS, path string) chan FileLine {
+	return readFileLines(path, func() (io.ReadCloser, error) {
+		return fsys.Open(path)
+	})
+}
+
+func readFileLines(path string, open func() (io.ReadCloser, error)) chan FileLine {
 	lines := make(chan FileLine)
 
+	metricFileLineReadersActive.Inc()
 	go func() {
+		defer metricFileLineReadersActive.Dec()
 		defer close(lines)
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			lines <- FileLine{Err: fmt.Errorf("Failed to retrieve absolute path for '%s': %v", path, err)}
-			return
-		}
 
-		file, err := os.Open(absPath)
+		file, err := open()
 		if err != nil {
 			lines <- FileLine{Err: fmt.Errorf("Failed to open file '%s': %v", path, err)}
 			return